@@ -1,20 +1,20 @@
 package agents
 
 import (
-	"bytes"
 	"context"
+	"easy-chat/agents/contextwindow"
 	"easy-chat/agents/llms"
+	"easy-chat/agents/memory"
 	"easy-chat/agents/prompts"
 	"easy-chat/agents/toolkit"
 	"easy-chat/consts"
-	"easy-chat/dao"
 	"easy-chat/request"
 	"errors"
 	"fmt"
 	"github.com/dlclark/regexp2"
 	"log"
+	"slices"
 	"strings"
-	"text/template"
 	"time"
 )
 
@@ -26,10 +26,22 @@ var (
 	ErrWhileMatchingRegex  = errors.New("error while matching regex")
 )
 
+// maxStepTokens caps each step of the scratchpad, mostly to cut long tool observations
+const maxStepTokens = 2000
+
 type Agent struct {
 	LLM     llms.LLM
 	Tools   []toolkit.Tool
 	MaxStep int
+	Memory  memory.Memory
+	// Assembler keeps the prompt of every step inside the model's context window, nil sends it as is
+	Assembler *contextwindow.Assembler
+	// UserMemories are facts about the user, they are the last to go when the prompt is too long
+	UserMemories []string
+	// SystemPrompt holds instructions from the user that are put above the ReAct instructions
+	SystemPrompt string
+	CallOptions  []llms.CallOption
+	StepFunc     StepFunc
 }
 
 type Step struct {
@@ -40,6 +52,9 @@ type Step struct {
 	FinalAnswer string `json:"final_answer"`
 }
 
+// StepFunc is called with every step the agent took, the final answer included
+type StepFunc func(step Step)
+
 func NewAgent(llm llms.LLM, tools []toolkit.Tool, options ...Option) (*Agent, error) {
 	opts := GetDefaultOptions()
 	for _, opt := range options {
@@ -47,9 +62,15 @@ func NewAgent(llm llms.LLM, tools []toolkit.Tool, options ...Option) (*Agent, er
 	}
 
 	return &Agent{
-		LLM:     llm,
-		Tools:   tools,
-		MaxStep: opts.MaxStep,
+		LLM:          llm,
+		Tools:        tools,
+		MaxStep:      opts.MaxStep,
+		Memory:       opts.Memory,
+		Assembler:    opts.Assembler,
+		UserMemories: opts.UserMemories,
+		SystemPrompt: opts.SystemPrompt,
+		CallOptions:  opts.CallOptions,
+		StepFunc:     opts.StepFunc,
 	}, nil
 }
 
@@ -57,15 +78,17 @@ func (a *Agent) Execute(ctx context.Context, request *request.ChatRequest) (stri
 	var finalAnswer string
 	var immediateSteps []Step
 	toolMap := a.buildToolMap()
+	chatHistory := a.getChatHistory(ctx, request)
 
 	for i := 0; i < a.MaxStep; i++ {
-		step, err := a.plan(ctx, request, immediateSteps)
+		step, err := a.plan(ctx, request, chatHistory, immediateSteps)
 		if err != nil {
 			log.Printf("%v: %v", ErrWhilePlanningStep, err)
 			return "", err
 		}
 
 		if step.FinalAnswer != "" {
+			a.reportStep(*step)
 			finalAnswer = step.FinalAnswer
 			break
 		}
@@ -81,12 +104,19 @@ func (a *Agent) Execute(ctx context.Context, request *request.ChatRequest) (stri
 			}
 		}
 
+		a.reportStep(*step)
 		immediateSteps = append(immediateSteps, *step)
 	}
 
 	return finalAnswer, nil
 }
 
+func (a *Agent) reportStep(step Step) {
+	if a.StepFunc != nil {
+		a.StepFunc(step)
+	}
+}
+
 func (a *Agent) buildToolMap() map[string]toolkit.Tool {
 	toolMap := make(map[string]toolkit.Tool)
 	for _, tool := range a.Tools {
@@ -95,23 +125,46 @@ func (a *Agent) buildToolMap() map[string]toolkit.Tool {
 	return toolMap
 }
 
-func (a *Agent) plan(ctx context.Context, request *request.ChatRequest, immediateSteps []Step) (*Step, error) {
-	currentTime := buildCurrentTime()
-	toolDetail := a.getToolDetail()
-	toolNames := a.getToolNames()
-	chatHistory := a.getChatHistory(request)
-	agentScratchpad := buildAgentScratchpad(immediateSteps)
-
-	prompt, err := renderPromptTemplate(prompts.ReActPromptTemplate, map[string]interface{}{
-		"max_step":         a.MaxStep,
-		"current_step":     len(immediateSteps) + 1,
-		"current_time":     currentTime,
-		"tool_detail":      toolDetail,
-		"tool_names":       toolNames,
-		"chat_history":     chatHistory,
-		"agent_scratchpad": agentScratchpad,
-		"question":         request.Query,
-	})
+func (a *Agent) plan(ctx context.Context, request *request.ChatRequest, chatHistory []string, immediateSteps []Step) (*Step, error) {
+	placeholders := map[string]interface{}{
+		"max_step":      a.MaxStep,
+		"current_step":  len(immediateSteps) + 1,
+		"current_time":  buildCurrentTime(),
+		"tool_detail":   a.getToolDetail(),
+		"tool_names":    a.getToolNames(),
+		"question":      request.Query,
+		"system_prompt": a.SystemPrompt,
+	}
+
+	history := &contextwindow.Part{Items: chatHistory, KeepLatest: true}
+	scratchpad := &contextwindow.Part{
+		Items:         buildAgentScratchpad(immediateSteps),
+		Priority:      1,
+		KeepLatest:    true,
+		MaxItemTokens: maxStepTokens,
+	}
+	userMemories := &contextwindow.Part{Items: formatUserMemories(a.UserMemories), Priority: 2}
+
+	callOptions := slices.Clone(a.CallOptions)
+	if a.Assembler != nil {
+		placeholders["chat_history"] = ""
+		placeholders["agent_scratchpad"] = ""
+		placeholders["user_memories"] = ""
+		fixed, err := prompts.Render(prompts.ReActPromptTemplate, placeholders)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := a.Assembler.Fit(fixed, history, scratchpad, userMemories); err != nil {
+			return nil, err
+		}
+		callOptions = append(callOptions, llms.WithMaxTokens(a.Assembler.ReservedTokens))
+	}
+
+	placeholders["chat_history"] = strings.Join(history.Items, "")
+	placeholders["agent_scratchpad"] = strings.Join(scratchpad.Items, "")
+	placeholders["user_memories"] = strings.Join(userMemories.Items, "")
+	prompt, err := prompts.Render(prompts.ReActPromptTemplate, placeholders)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +174,8 @@ func (a *Agent) plan(ctx context.Context, request *request.ChatRequest, immediat
 		return nil, fmt.Errorf("%w: %s", consts.ErrInvalidContextKey, consts.KeyStreamFunc)
 	}
 
-	result, err := a.LLM.GenerateContent(ctx, prompt, llms.WithStreamFunc(streamFunc))
+	callOptions = append(callOptions, llms.WithStreamFunc(streamFunc))
+	result, err := a.LLM.GenerateContent(ctx, prompt, callOptions...)
 	if err != nil {
 		return nil, err
 	}
@@ -154,19 +208,31 @@ func (a *Agent) getToolNames() string {
 	return strings.Join(names, ",")
 }
 
-func (a *Agent) getChatHistory(request *request.ChatRequest) string {
-	var result strings.Builder
+// getChatHistory returns a line per remembered message
+func (a *Agent) getChatHistory(ctx context.Context, request *request.ChatRequest) []string {
+	if a.Memory == nil {
+		return nil
+	}
 
-	chatHistories, err := dao.GetChatHistoryBySessionID(request.SessionID)
+	messages, err := a.Memory.LoadMessages(ctx, request.SessionID, request.Query)
 	if err != nil {
-		return ""
+		return nil
 	}
 
-	for _, chatHistory := range chatHistories {
-		result.WriteString(chatHistory.MessageType + ": " + chatHistory.Content + "\n")
+	lines := make([]string, len(messages))
+	for i, message := range messages {
+		lines[i] = message.Role + ": " + message.Content + "\n"
 	}
 
-	return result.String()
+	return lines
+}
+
+func formatUserMemories(userMemories []string) []string {
+	lines := make([]string, len(userMemories))
+	for i, userMemory := range userMemories {
+		lines[i] = "- " + userMemory + "\n"
+	}
+	return lines
 }
 
 func buildCurrentTime() string {
@@ -174,16 +240,19 @@ func buildCurrentTime() string {
 	return currentTime.Format(time.RFC3339)
 }
 
-func buildAgentScratchpad(immediateSteps []Step) string {
-	var result strings.Builder
-	for _, step := range immediateSteps {
+// buildAgentScratchpad returns the text of every step taken so far
+func buildAgentScratchpad(immediateSteps []Step) []string {
+	steps := make([]string, len(immediateSteps))
+	for i, step := range immediateSteps {
+		var result strings.Builder
 		result.WriteString("Thought: " + step.Thought + "\n")
 		appendFieldIfNotEmpty(&result, "Action", step.Action)
 		appendFieldIfNotEmpty(&result, "Action Input", step.ActionInput)
 		appendFieldIfNotEmpty(&result, "Observation", step.Observation)
 		result.WriteString("\n")
+		steps[i] = result.String()
 	}
-	return result.String()
+	return steps
 }
 
 func appendFieldIfNotEmpty(builder *strings.Builder, fieldName, fieldValue string) {
@@ -192,20 +261,6 @@ func appendFieldIfNotEmpty(builder *strings.Builder, fieldName, fieldValue strin
 	}
 }
 
-func renderPromptTemplate(promptTemplate string, placeholders map[string]interface{}) (string, error) {
-	tmpl, err := template.New("react_prompt_template").Parse(promptTemplate)
-	if err != nil {
-		return "", err
-	}
-
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, placeholders); err != nil {
-		return "", err
-	}
-
-	return buf.String(), nil
-}
-
 func parseOutput(output string) (*Step, error) {
 	step := &Step{}
 
@@ -0,0 +1,102 @@
+// Package contextwindow fits the parts of a prompt into a model's context window
+package contextwindow
+
+import (
+	"easy-chat/agents/tokenizer"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrContextWindowExceeded = errors.New("prompt does not fit into the context window")
+
+const truncationMark = "...(truncated)"
+
+// Part is a section of a prompt made of items that can be dropped one by one,
+// such as the messages of a history or the steps of a scratchpad
+type Part struct {
+	Items []string
+	// Priority decides the order parts shrink in when the prompt is too long, lower shrinks first
+	Priority int
+	// KeepLatest drops items from the start instead of the end, for parts where the last items matter most
+	KeepLatest bool
+	// MaxItemTokens cuts every item down to that many tokens, 0 means no limit
+	MaxItemTokens int
+}
+
+// Assembler keeps prompts within ContextWindow minus the tokens reserved for the answer
+type Assembler struct {
+	Tokenizer      tokenizer.Tokenizer
+	ContextWindow  int
+	ReservedTokens int
+}
+
+func NewAssembler(tokenizer tokenizer.Tokenizer, contextWindow, reservedTokens int) *Assembler {
+	return &Assembler{
+		Tokenizer:      tokenizer,
+		ContextWindow:  contextWindow,
+		ReservedTokens: reservedTokens,
+	}
+}
+
+// Budget is how many tokens the prompt may take up
+func (a *Assembler) Budget() int {
+	return a.ContextWindow - a.ReservedTokens
+}
+
+// Fit shrinks the parts in place until they fit next to the fixed text, which is everything
+// in the prompt that can not be left out. Long items are truncated first, then items are
+// dropped from the parts in priority order.
+func (a *Assembler) Fit(fixed string, parts ...*Part) error {
+	used := a.Tokenizer.CountTokens(fixed)
+	if used > a.Budget() {
+		return fmt.Errorf("%w: %d tokens can not be left out, %d allowed", ErrContextWindowExceeded, used, a.Budget())
+	}
+
+	counts := make([][]int, len(parts))
+	for i, part := range parts {
+		counts[i] = make([]int, len(part.Items))
+		for j, item := range part.Items {
+			if part.MaxItemTokens > 0 {
+				item = a.truncate(item, part.MaxItemTokens)
+				part.Items[j] = item
+			}
+			counts[i][j] = a.Tokenizer.CountTokens(item)
+			used += counts[i][j]
+		}
+	}
+
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(x, y int) int {
+		return parts[x].Priority - parts[y].Priority
+	})
+
+	for _, i := range order {
+		part := parts[i]
+		for used > a.Budget() && len(part.Items) > 0 {
+			if part.KeepLatest {
+				used -= counts[i][0]
+				part.Items, counts[i] = part.Items[1:], counts[i][1:]
+			} else {
+				last := len(part.Items) - 1
+				used -= counts[i][last]
+				part.Items, counts[i] = part.Items[:last], counts[i][:last]
+			}
+		}
+	}
+
+	if used > a.Budget() {
+		return fmt.Errorf("%w: %d tokens, %d allowed", ErrContextWindowExceeded, used, a.Budget())
+	}
+	return nil
+}
+
+func (a *Assembler) truncate(text string, maxTokens int) string {
+	if a.Tokenizer.CountTokens(text) <= maxTokens {
+		return text
+	}
+	return a.Tokenizer.Truncate(text, maxTokens-a.Tokenizer.CountTokens(truncationMark)) + truncationMark
+}
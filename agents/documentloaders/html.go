@@ -0,0 +1,64 @@
+package documentloaders
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var _ Loader = HTML{}
+
+// HTML loads the visible text of a page, keeping block elements on their own lines
+type HTML struct{}
+
+var skippedElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"template": true,
+	"head":     true,
+}
+
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"pre": true, "blockquote": true, "section": true, "article": true, "table": true,
+}
+
+func (HTML) Load(content []byte) (string, error) {
+	root, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && skippedElements[node.Data] {
+			return
+		}
+		if node.Type == html.TextNode {
+			if line := strings.Join(strings.Fields(node.Data), " "); line != "" {
+				text.WriteString(line)
+				text.WriteString(" ")
+			}
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if node.Type == html.ElementNode && blockElements[node.Data] {
+			text.WriteString("\n")
+		}
+	}
+	walk(root)
+
+	lines := strings.Split(text.String(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n"), nil
+}
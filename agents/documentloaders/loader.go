@@ -0,0 +1,54 @@
+// Package documentloaders extracts plain text from uploaded files
+package documentloaders
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrUnsupportedFileType = errors.New("unsupported file type")
+	ErrEmptyDocument       = errors.New("document has no text")
+	ErrInvalidEncoding     = errors.New("text is not valid utf-8")
+)
+
+type Loader interface {
+	Load(content []byte) (string, error)
+}
+
+var loaders = map[string]Loader{
+	".txt":      Text{},
+	".md":       Text{},
+	".markdown": Text{},
+	".html":     HTML{},
+	".htm":      HTML{},
+	".pdf":      PDF{},
+}
+
+// SupportedExtensions lists the file extensions LoadFile knows how to read
+func SupportedExtensions() []string {
+	return []string{".txt", ".md", ".markdown", ".html", ".htm", ".pdf"}
+}
+
+// LoadFile picks a loader by the file extension and returns the text of the file
+func LoadFile(fileName string, content []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	loader, exists := loaders[ext]
+	if !exists {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFileType, ext)
+	}
+
+	text, err := loader.Load(content)
+	if err != nil {
+		return "", err
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", ErrEmptyDocument
+	}
+
+	return text, nil
+}
@@ -0,0 +1,38 @@
+package documentloaders
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+var _ Loader = PDF{}
+
+// PDF loads the text layer of every page, scanned pages without one come out empty
+type PDF struct{}
+
+func (PDF) Load(content []byte) (string, error) {
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		pageText, err := page.GetPlainText(nil)
+		if err != nil {
+			return "", err
+		}
+
+		text.WriteString(pageText)
+		text.WriteString("\n\n")
+	}
+
+	return text.String(), nil
+}
@@ -0,0 +1,19 @@
+package documentloaders
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+var _ Loader = Text{}
+
+// Text loads plain text and markdown as is
+type Text struct{}
+
+func (Text) Load(content []byte) (string, error) {
+	if !utf8.Valid(content) {
+		return "", ErrInvalidEncoding
+	}
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	return strings.ReplaceAll(text, "\r\n", "\n"), nil
+}
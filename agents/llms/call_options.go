@@ -4,9 +4,33 @@ import "context"
 
 type StreamFunc func(ctx context.Context, chunk []byte) error
 
+// Usage is the number of tokens a call to the LLM was billed for
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// UsageFunc is told the usage of every call, once the answer is complete
+type UsageFunc func(usage Usage)
+
+// Image is a picture sent along with the prompt, only models that accept images can be given one
+type Image struct {
+	MIMEType string
+	Data     []byte
+}
+
 // CallOptions Common parameters while calling LLM
 type CallOptions struct {
 	StreamFunc StreamFunc
+	// MaxTokens caps the length of the answer, 0 leaves it to the model
+	MaxTokens int
+	// SystemPrompt is sent ahead of the prompt as the model's instructions
+	SystemPrompt string
+	// Temperature and TopP are left to the model when nil
+	Temperature *float64
+	TopP        *float64
+	UsageFunc   UsageFunc
+	Images      []Image
 }
 
 type CallOption func(*CallOptions)
@@ -16,3 +40,40 @@ func WithStreamFunc(streamFunc StreamFunc) CallOption {
 		o.StreamFunc = streamFunc
 	}
 }
+
+func WithMaxTokens(maxTokens int) CallOption {
+	return func(o *CallOptions) {
+		o.MaxTokens = maxTokens
+	}
+}
+
+func WithSystemPrompt(systemPrompt string) CallOption {
+	return func(o *CallOptions) {
+		o.SystemPrompt = systemPrompt
+	}
+}
+
+func WithTemperature(temperature float64) CallOption {
+	return func(o *CallOptions) {
+		o.Temperature = &temperature
+	}
+}
+
+func WithTopP(topP float64) CallOption {
+	return func(o *CallOptions) {
+		o.TopP = &topP
+	}
+}
+
+func WithUsageFunc(usageFunc UsageFunc) CallOption {
+	return func(o *CallOptions) {
+		o.UsageFunc = usageFunc
+	}
+}
+
+// WithImages sends the images with the prompt, in the multimodal format of the provider
+func WithImages(images ...Image) CallOption {
+	return func(o *CallOptions) {
+		o.Images = append(o.Images, images...)
+	}
+}
@@ -26,9 +26,63 @@ type client struct {
 	apiKey     string
 }
 
+const (
+	textGenerationURL       = "https://dashscope.aliyuncs.com/api/v1/services/aigc/text-generation/generation"
+	multimodalGenerationURL = "https://dashscope.aliyuncs.com/api/v1/services/aigc/multimodal-generation/generation"
+)
+
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// Content is the text of a message. The multimodal API takes every message of a request
+// as a list of parts, images included, and answers with a list of text parts.
+type Content struct {
+	Text string
+	// Images are URLs or data URIs
+	Images []string
+
+	asParts bool
+}
+
+type contentPart struct {
+	Image string `json:"image,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+func (c Content) MarshalJSON() ([]byte, error) {
+	if !c.asParts && len(c.Images) == 0 {
+		return json.Marshal(c.Text)
+	}
+
+	parts := make([]contentPart, 0, len(c.Images)+1)
+	for _, image := range c.Images {
+		parts = append(parts, contentPart{Image: image})
+	}
+	parts = append(parts, contentPart{Text: c.Text})
+	return json.Marshal(parts)
+}
+
+func (c *Content) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '[' {
+		return json.Unmarshal(data, &c.Text)
+	}
+
+	var parts []contentPart
+	if err := json.Unmarshal(data, &parts); err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	for _, part := range parts {
+		if part.Image != "" {
+			c.Images = append(c.Images, part.Image)
+		}
+		text.WriteString(part.Text)
+	}
+	c.Text = text.String()
+	return nil
 }
 
 type Input struct {
@@ -36,8 +90,11 @@ type Input struct {
 }
 
 type Parameters struct {
-	ResultFormat      string `json:"result_format"`
-	IncrementalOutput bool   `json:"incremental_output"`
+	ResultFormat      string   `json:"result_format"`
+	IncrementalOutput bool     `json:"incremental_output"`
+	MaxTokens         int      `json:"max_tokens,omitempty"`
+	Temperature       *float64 `json:"temperature,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
 }
 
 type ChatRequest struct {
@@ -48,6 +105,16 @@ type ChatRequest struct {
 	StreamFunc llms.StreamFunc `json:"-"`
 }
 
+// isMultimodal tells whether a message of the request has images, which only the multimodal API takes
+func (r *ChatRequest) isMultimodal() bool {
+	for _, message := range r.Input.Messages {
+		if len(message.Content.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 type ChatResponse struct {
 	RequestID string `json:"request_id"`
 	Output    struct {
@@ -75,7 +142,13 @@ func newClient(apiKey string) (*client, error) {
 }
 
 func (c *client) createChat(ctx context.Context, chatRequest *ChatRequest) (*ChatResponse, error) {
-	url := "https://dashscope.aliyuncs.com/api/v1/services/aigc/text-generation/generation"
+	url := textGenerationURL
+	if chatRequest.isMultimodal() {
+		url = multimodalGenerationURL
+		for i := range chatRequest.Input.Messages {
+			chatRequest.Input.Messages[i].Content.asParts = true
+		}
+	}
 
 	reqBody, err := json.Marshal(chatRequest)
 	if err != nil {
@@ -150,7 +223,7 @@ func handleStreamResponse(ctx context.Context, chatRequest *ChatRequest, resp *h
 	var completeResponse *ChatResponse
 	var contentBuilder strings.Builder
 	for partialResponse := range dataChan {
-		content := partialResponse.Output.Choices[0].Message.Content
+		content := partialResponse.Output.Choices[0].Message.Content.Text
 		if err := callStreamFunc(ctx, chatRequest.StreamFunc, content); err != nil {
 			log.Printf("%v: %v", ErrWhileCallingStreamFunc, err)
 		}
@@ -159,7 +232,7 @@ func handleStreamResponse(ctx context.Context, chatRequest *ChatRequest, resp *h
 		completeResponse = partialResponse
 	}
 
-	completeResponse.Output.Choices[0].Message.Content = contentBuilder.String()
+	completeResponse.Output.Choices[0].Message.Content = Content{Text: contentBuilder.String()}
 
 	return completeResponse, nil
 }
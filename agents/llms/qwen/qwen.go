@@ -3,7 +3,9 @@ package qwen
 import (
 	"context"
 	"easy-chat/agents/llms"
+	"encoding/base64"
 	"errors"
+	"strings"
 )
 
 var _ llms.LLM = (*LLM)(nil)
@@ -12,6 +14,19 @@ var (
 	ErrMissedModelName = errors.New("missed model name")
 )
 
+// visionModelPrefixes are the model families that accept images
+var visionModelPrefixes = []string{"qwen-vl", "qwen2-vl", "qwen2.5-vl", "qwen3-vl", "qvq", "qwen-omni", "qwen2.5-omni"}
+
+// SupportsImages tells whether the model takes images with the prompt
+func SupportsImages(model string) bool {
+	for _, prefix := range visionModelPrefixes {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 type LLM struct {
 	client    *client
 	ModelName string
@@ -45,14 +60,28 @@ func (l *LLM) GenerateContent(ctx context.Context, prompt string, options ...llm
 		opt(opts)
 	}
 
+	var messages []Message
+	if opts.SystemPrompt != "" {
+		messages = append(messages, Message{Role: "system", Content: Content{Text: opts.SystemPrompt}})
+	}
+
+	images := make([]string, len(opts.Images))
+	for i, image := range opts.Images {
+		images[i] = "data:" + image.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(image.Data)
+	}
+	messages = append(messages, Message{Role: "user", Content: Content{Text: prompt, Images: images}})
+
 	chatRequest := &ChatRequest{
 		Model: l.ModelName,
 		Input: Input{
-			Messages: []Message{{Role: "user", Content: prompt}},
+			Messages: messages,
 		},
 		Parameters: Parameters{
 			ResultFormat:      "message",
 			IncrementalOutput: opts.StreamFunc != nil,
+			MaxTokens:         opts.MaxTokens,
+			Temperature:       opts.Temperature,
+			TopP:              opts.TopP,
 		},
 		StreamFunc: opts.StreamFunc,
 	}
@@ -62,7 +91,14 @@ func (l *LLM) GenerateContent(ctx context.Context, prompt string, options ...llm
 		return "", err
 	}
 
-	content := result.Output.Choices[0].Message.Content
+	if opts.UsageFunc != nil {
+		opts.UsageFunc(llms.Usage{
+			PromptTokens:     result.Usage.InputTokens,
+			CompletionTokens: result.Usage.OutputTokens,
+		})
+	}
+
+	content := result.Output.Choices[0].Message.Content.Text
 
 	return content, nil
 }
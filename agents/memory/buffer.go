@@ -0,0 +1,22 @@
+package memory
+
+import "context"
+
+var _ Memory = (*Buffer)(nil)
+
+// Buffer remembers the whole conversation
+type Buffer struct {
+	History History
+}
+
+func NewBuffer(history History) *Buffer {
+	return &Buffer{History: history}
+}
+
+func (m *Buffer) LoadMessages(ctx context.Context, sessionID, query string) ([]Message, error) {
+	chatHistories, err := m.History.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return toMessages(chatHistories), nil
+}
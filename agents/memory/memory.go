@@ -1,11 +1,65 @@
 package memory
 
+import (
+	"context"
+	"easy-chat/entity"
+)
+
 const (
 	MessageRoleAI   = "ai"
 	MessageRoleUser = "user"
+	// MessageRoleSystem marks messages written by a memory, such as the summary of older turns
+	MessageRoleSystem = "system"
+)
+
+// strategies a session can pick its memory from
+const (
+	StrategyBuffer      = "buffer"
+	StrategyWindow      = "window"
+	StrategyTokenBuffer = "token_buffer"
+	StrategySummary     = "summary"
+	StrategyVector      = "vector"
 )
 
 type Message struct {
 	Role    string
 	Content string
 }
+
+// Memory decides which part of a conversation is put into the prompt for the next query
+type Memory interface {
+	LoadMessages(ctx context.Context, sessionID, query string) ([]Message, error)
+}
+
+// History is where memories read the messages of a session from, oldest first.
+// dao.HistoryRepository satisfies it.
+type History interface {
+	GetBySessionID(sessionID string) ([]*entity.ChatHistory, error)
+}
+
+// Strategies lists every strategy a session can pick
+func Strategies() []string {
+	return []string{StrategyBuffer, StrategyWindow, StrategyTokenBuffer, StrategySummary, StrategyVector}
+}
+
+func toMessages(chatHistories []*entity.ChatHistory) []Message {
+	messages := make([]Message, len(chatHistories))
+	for i, chatHistory := range chatHistories {
+		messages[i] = Message{
+			Role:    chatHistory.MessageType,
+			Content: chatHistory.Content,
+		}
+	}
+	return messages
+}
+
+// lastN returns the last n items, or all of them if there are fewer
+func lastN(chatHistories []*entity.ChatHistory, n int) []*entity.ChatHistory {
+	if n < 0 {
+		n = 0
+	}
+	if len(chatHistories) <= n {
+		return chatHistories
+	}
+	return chatHistories[len(chatHistories)-n:]
+}
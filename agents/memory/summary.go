@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"easy-chat/agents/llms"
+	"easy-chat/agents/prompts"
+	"easy-chat/entity"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+)
+
+var _ Memory = (*Summary)(nil)
+
+var ErrFailedToSummarize = errors.New("failed to summarize conversation")
+
+// SummaryStore keeps the rolling summary of a session and the ID of the last message folded into it
+type SummaryStore interface {
+	LoadSummary(sessionID string) (summary string, untilID uint, err error)
+	SaveSummary(sessionID string, summary string, untilID uint) error
+}
+
+// Summary remembers the last BufferSize messages word for word and everything before them
+// as a summary written by the LLM. Only messages that left the buffer since the last call
+// are summarized, so every message is sent to the LLM once. Switching to another branch
+// of the conversation starts the summary over.
+type Summary struct {
+	History    History
+	Store      SummaryStore
+	LLM        llms.LLM
+	BufferSize int
+}
+
+func NewSummary(history History, store SummaryStore, llm llms.LLM, bufferSize int) *Summary {
+	return &Summary{
+		History:    history,
+		Store:      store,
+		LLM:        llm,
+		BufferSize: bufferSize,
+	}
+}
+
+func (m *Summary) LoadMessages(ctx context.Context, sessionID, query string) ([]Message, error) {
+	chatHistories, err := m.History.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := lastN(chatHistories, m.BufferSize)
+	older := chatHistories[:len(chatHistories)-len(recent)]
+
+	summary, untilID, err := m.Store.LoadSummary(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	// the summary was written on another branch of the conversation, so it is started over
+	if untilID != 0 && !slices.ContainsFunc(chatHistories, func(chatHistory *entity.ChatHistory) bool {
+		return chatHistory.ID == untilID
+	}) {
+		summary, untilID = "", 0
+	}
+
+	var pending []*entity.ChatHistory
+	for _, chatHistory := range older {
+		if chatHistory.ID > untilID {
+			pending = append(pending, chatHistory)
+		}
+	}
+
+	if len(pending) > 0 {
+		// a stale summary still beats failing the chat, the pending messages are retried next time
+		if newSummary, err := m.summarize(ctx, summary, pending); err != nil {
+			log.Printf("%v: %v", ErrFailedToSummarize, err)
+		} else if err := m.Store.SaveSummary(sessionID, newSummary, pending[len(pending)-1].ID); err != nil {
+			log.Printf("%v: %v", ErrFailedToSummarize, err)
+		} else {
+			summary = newSummary
+		}
+	}
+
+	messages := make([]Message, 0, len(recent)+1)
+	if summary != "" {
+		messages = append(messages, Message{
+			Role:    MessageRoleSystem,
+			Content: "Summary of the earlier conversation: " + summary,
+		})
+	}
+	return append(messages, toMessages(recent)...), nil
+}
+
+func (m *Summary) summarize(ctx context.Context, summary string, chatHistories []*entity.ChatHistory) (string, error) {
+	var newLines strings.Builder
+	for _, chatHistory := range chatHistories {
+		newLines.WriteString(chatHistory.MessageType + ": " + chatHistory.Content + "\n")
+	}
+
+	prompt, err := prompts.Render(prompts.ConversationSummaryPromptTemplate, map[string]interface{}{
+		"summary":   summary,
+		"new_lines": newLines.String(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	result, err := m.LLM.GenerateContent(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	result = strings.TrimSpace(result)
+	if result == "" {
+		return "", ErrFailedToSummarize
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"easy-chat/agents/tokenizer"
+)
+
+var _ Memory = (*TokenBuffer)(nil)
+
+// TokenBuffer remembers as many of the latest messages as fit into MaxTokens
+type TokenBuffer struct {
+	History   History
+	Tokenizer tokenizer.Tokenizer
+	MaxTokens int
+}
+
+func NewTokenBuffer(history History, tokenizer tokenizer.Tokenizer, maxTokens int) *TokenBuffer {
+	return &TokenBuffer{
+		History:   history,
+		Tokenizer: tokenizer,
+		MaxTokens: maxTokens,
+	}
+}
+
+func (m *TokenBuffer) LoadMessages(ctx context.Context, sessionID, query string) ([]Message, error) {
+	chatHistories, err := m.History.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	used := 0
+	start := len(chatHistories)
+	for start > 0 {
+		tokens := m.Tokenizer.CountTokens(chatHistories[start-1].Content)
+		if used+tokens > m.MaxTokens {
+			break
+		}
+		used += tokens
+		start--
+	}
+
+	return toMessages(chatHistories[start:]), nil
+}
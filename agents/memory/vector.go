@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"easy-chat/agents/embedExecutors"
+	"easy-chat/agents/vectorstores"
+	"easy-chat/entity"
+	"maps"
+	"strconv"
+)
+
+var _ Memory = (*VectorRetrieval)(nil)
+
+// VectorRetrieval remembers the last BufferSize messages plus the older turns most similar to the query.
+// It expects the messages to be embedded into Collection with their chat_history ID as document ID
+// and a session_id metadata.
+type VectorRetrieval struct {
+	History       History
+	VectorStore   vectorstores.VectorStore
+	EmbedExecutor embedExecutors.EmbedExecutor
+	Collection    string
+	// Filter narrows the search further, e.g. to embeddings of the current model
+	Filter     map[string]string
+	TopK       int
+	BufferSize int
+}
+
+func NewVectorRetrieval(history History, vectorStore vectorstores.VectorStore, embedExecutor embedExecutors.EmbedExecutor, collection string, filter map[string]string, topK, bufferSize int) *VectorRetrieval {
+	return &VectorRetrieval{
+		History:       history,
+		VectorStore:   vectorStore,
+		EmbedExecutor: embedExecutor,
+		Collection:    collection,
+		Filter:        filter,
+		TopK:          topK,
+		BufferSize:    bufferSize,
+	}
+}
+
+func (m *VectorRetrieval) LoadMessages(ctx context.Context, sessionID, query string) ([]Message, error) {
+	chatHistories, err := m.History.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := lastN(chatHistories, m.BufferSize)
+	older := chatHistories[:len(chatHistories)-len(recent)]
+	if len(older) == 0 || query == "" {
+		return toMessages(recent), nil
+	}
+
+	vectors, err := m.EmbedExecutor.GenerateEmbeddings(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return toMessages(recent), nil
+	}
+
+	filter := maps.Clone(m.Filter)
+	if filter == nil {
+		filter = make(map[string]string)
+	}
+	filter["session_id"] = sessionID
+
+	// the recent messages are searched too, so ask for enough results to still find TopK older ones
+	results, err := m.VectorStore.Search(ctx, m.Collection, vectors[0],
+		vectorstores.WithTopK(m.TopK+len(recent)),
+		vectorstores.WithFilter(filter),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make(map[uint]int, len(older))
+	for i, chatHistory := range older {
+		positions[chatHistory.ID] = i
+	}
+
+	selected := make([]bool, len(older))
+	found := 0
+	for _, result := range results {
+		if found == m.TopK {
+			break
+		}
+		messageID, err := strconv.ParseUint(result.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		position, exists := positions[uint(messageID)]
+		if !exists {
+			continue
+		}
+		found++
+
+		// keep question and answer together so the turn makes sense on its own
+		selected[position] = true
+		if older[position].MessageType == MessageRoleUser && position+1 < len(older) {
+			selected[position+1] = true
+		} else if older[position].MessageType == MessageRoleAI && position > 0 {
+			selected[position-1] = true
+		}
+	}
+
+	relevant := make([]*entity.ChatHistory, 0, 2*found+len(recent))
+	for i, chatHistory := range older {
+		if selected[i] {
+			relevant = append(relevant, chatHistory)
+		}
+	}
+
+	return toMessages(append(relevant, recent...)), nil
+}
@@ -0,0 +1,23 @@
+package memory
+
+import "context"
+
+var _ Memory = (*Window)(nil)
+
+// Window remembers the last Size messages
+type Window struct {
+	History History
+	Size    int
+}
+
+func NewWindow(history History, size int) *Window {
+	return &Window{History: history, Size: size}
+}
+
+func (m *Window) LoadMessages(ctx context.Context, sessionID, query string) ([]Message, error) {
+	chatHistories, err := m.History.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return toMessages(lastN(chatHistories, m.Size)), nil
+}
@@ -1,9 +1,21 @@
 package agents
 
+import (
+	"easy-chat/agents/contextwindow"
+	"easy-chat/agents/llms"
+	"easy-chat/agents/memory"
+)
+
 const defaultMaxStep = 5
 
 type Options struct {
-	MaxStep int
+	MaxStep      int
+	Memory       memory.Memory
+	Assembler    *contextwindow.Assembler
+	UserMemories []string
+	SystemPrompt string
+	CallOptions  []llms.CallOption
+	StepFunc     StepFunc
 }
 
 func GetDefaultOptions() *Options {
@@ -19,3 +31,44 @@ func WithMaxStep(maxStep int) Option {
 		o.MaxStep = maxStep
 	}
 }
+
+// WithMemory sets what the agent remembers of the conversation, without it the agent sees only the query
+func WithMemory(mem memory.Memory) Option {
+	return func(o *Options) {
+		o.Memory = mem
+	}
+}
+
+func WithAssembler(assembler *contextwindow.Assembler) Option {
+	return func(o *Options) {
+		o.Assembler = assembler
+	}
+}
+
+// WithUserMemories gives the agent facts it remembers about the user from earlier conversations
+func WithUserMemories(userMemories []string) Option {
+	return func(o *Options) {
+		o.UserMemories = userMemories
+	}
+}
+
+// WithSystemPrompt adds instructions the agent follows on top of its own
+func WithSystemPrompt(systemPrompt string) Option {
+	return func(o *Options) {
+		o.SystemPrompt = systemPrompt
+	}
+}
+
+// WithCallOptions sets generation parameters, such as the temperature, for every step
+func WithCallOptions(callOptions ...llms.CallOption) Option {
+	return func(o *Options) {
+		o.CallOptions = callOptions
+	}
+}
+
+// WithStepFunc has the agent report every step it takes, for keeping a trace of how it got to the answer
+func WithStepFunc(stepFunc StepFunc) Option {
+	return func(o *Options) {
+		o.StepFunc = stepFunc
+	}
+}
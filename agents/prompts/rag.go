@@ -0,0 +1,13 @@
+package prompts
+
+const RAGPromptTemplate = `
+	Answer the user's query using the document excerpts below.
+	Each excerpt starts with its number in square brackets. Cite the excerpts
+	you use by their numbers, e.g. [1] or [2][3], right after the statement they support.
+	If the excerpts do not contain the answer, say so instead of making one up.
+
+	Document Excerpts:
+	{{.documents}}
+
+	{{.conversation}}
+`
@@ -2,6 +2,10 @@ package prompts
 
 const ReActPromptTemplate = `
 	You are an AI agent that needs to think step by step.
+	{{if .system_prompt}}
+	Follow these instructions from the user throughout:
+	{{.system_prompt}}
+	{{end}}
 
 	You are allowed a maximum of {{.max_step}} steps to solve the problem.
 	If you reach the maximum number of steps without finding a solution, you must provide the best answer you have so far.
@@ -13,6 +17,9 @@ const ReActPromptTemplate = `
 	You have access to the following tools:
 	{{.tool_detail}}
 	
+	Known Facts About The User:
+	{{.user_memories}}
+	
 	Chat History:
 	{{.chat_history}}
 	
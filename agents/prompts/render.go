@@ -0,0 +1,21 @@
+package prompts
+
+import (
+	"bytes"
+	"text/template"
+)
+
+// Render fills the placeholders of a prompt template
+func Render(promptTemplate string, placeholders map[string]interface{}) (string, error) {
+	tmpl, err := template.New("prompt_template").Parse(promptTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, placeholders); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
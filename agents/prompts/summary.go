@@ -0,0 +1,13 @@
+package prompts
+
+const ConversationSummaryPromptTemplate = `
+	Progressively summarize the lines of conversation below, adding onto the current summary.
+	Keep names, numbers, decisions and open questions, drop small talk.
+	Use the same language as the conversation and reply with the new summary only.
+
+	Current Summary:
+	{{.summary}}
+
+	New Lines of Conversation:
+	{{.new_lines}}
+`
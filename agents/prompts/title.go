@@ -0,0 +1,13 @@
+package prompts
+
+const SessionTitlePromptTemplate = `
+	Write a short title for the conversation below.
+
+	The title must be at most 8 words, use the same language as the user,
+	and must not contain quotes, punctuation at the end or any explanation.
+	Reply with the title only.
+
+	User: {{.question}}
+
+	AI: {{.answer}}
+`
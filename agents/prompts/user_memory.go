@@ -0,0 +1,19 @@
+package prompts
+
+const UserMemoryExtractionPromptTemplate = `
+	You maintain a list of durable facts about a user, such as their preferences,
+	their job, the projects they work on and how they like to be answered.
+
+	Known Facts:
+	{{.memories}}
+
+	Read the latest exchange below and list the new facts it reveals about the user.
+	Only keep facts that will still be true and useful in future conversations,
+	skip anything already known, temporary or about other people.
+	Write every fact as a short sentence in the user's language.
+	Reply with a JSON array of strings only, e.g. ["Prefers Go examples"], or [] if there is nothing new.
+
+	User: {{.question}}
+
+	AI: {{.answer}}
+`
@@ -0,0 +1,40 @@
+package textsplitter
+
+const (
+	defaultChunkSize    = 800
+	defaultChunkOverlap = 100
+)
+
+type Options struct {
+	Separators   []string
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+func GetDefaultOptions() *Options {
+	return &Options{
+		Separators:   []string{"\n\n", "\n", "。", ". ", "！", "？", "; ", " ", ""},
+		ChunkSize:    defaultChunkSize,
+		ChunkOverlap: defaultChunkOverlap,
+	}
+}
+
+type Option func(*Options)
+
+func WithSeparators(separators []string) Option {
+	return func(o *Options) {
+		o.Separators = separators
+	}
+}
+
+func WithChunkSize(chunkSize int) Option {
+	return func(o *Options) {
+		o.ChunkSize = chunkSize
+	}
+}
+
+func WithChunkOverlap(chunkOverlap int) Option {
+	return func(o *Options) {
+		o.ChunkOverlap = chunkOverlap
+	}
+}
@@ -0,0 +1,124 @@
+// Package textsplitter cuts long texts into chunks small enough to embed
+package textsplitter
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidChunkOverlap = errors.New("chunk overlap must be smaller than chunk size")
+
+type TextSplitter interface {
+	SplitText(text string) ([]string, error)
+}
+
+var _ TextSplitter = (*RecursiveCharacter)(nil)
+
+// RecursiveCharacter splits on the coarsest separator that yields small enough pieces,
+// e.g. paragraphs first, then lines, then sentences, and merges neighbours back up to the chunk size.
+// Sizes are counted in runes.
+type RecursiveCharacter struct {
+	Separators   []string
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+func NewRecursiveCharacter(options ...Option) *RecursiveCharacter {
+	opts := GetDefaultOptions()
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	return &RecursiveCharacter{
+		Separators:   opts.Separators,
+		ChunkSize:    opts.ChunkSize,
+		ChunkOverlap: opts.ChunkOverlap,
+	}
+}
+
+func (s *RecursiveCharacter) SplitText(text string) ([]string, error) {
+	if s.ChunkOverlap >= s.ChunkSize {
+		return nil, ErrInvalidChunkOverlap
+	}
+	return s.split(text, s.Separators), nil
+}
+
+func (s *RecursiveCharacter) split(text string, separators []string) []string {
+	separator := ""
+	var remaining []string
+	for i, candidate := range separators {
+		if candidate == "" || strings.Contains(text, candidate) {
+			separator = candidate
+			remaining = separators[i+1:]
+			break
+		}
+	}
+
+	var chunks []string
+	var pieces []string
+	for _, piece := range splitKeepingSeparator(text, separator) {
+		if utf8.RuneCountInString(piece) <= s.ChunkSize {
+			pieces = append(pieces, piece)
+			continue
+		}
+
+		chunks = append(chunks, s.merge(pieces)...)
+		pieces = nil
+		if len(remaining) == 0 {
+			chunks = append(chunks, splitRunes(piece, s.ChunkSize)...)
+		} else {
+			chunks = append(chunks, s.split(piece, remaining)...)
+		}
+	}
+
+	return append(chunks, s.merge(pieces)...)
+}
+
+// merge joins consecutive pieces into chunks of at most ChunkSize runes,
+// starting every chunk with the tail of the previous one up to ChunkOverlap runes
+func (s *RecursiveCharacter) merge(pieces []string) []string {
+	var chunks []string
+	var current []string
+	size := 0
+
+	for _, piece := range pieces {
+		pieceSize := utf8.RuneCountInString(piece)
+		if size+pieceSize > s.ChunkSize && len(current) > 0 {
+			if chunk := strings.TrimSpace(strings.Join(current, "")); chunk != "" {
+				chunks = append(chunks, chunk)
+			}
+			for size > s.ChunkOverlap || (size+pieceSize > s.ChunkSize && size > 0) {
+				size -= utf8.RuneCountInString(current[0])
+				current = current[1:]
+			}
+		}
+		current = append(current, piece)
+		size += pieceSize
+	}
+
+	if chunk := strings.TrimSpace(strings.Join(current, "")); chunk != "" {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// splitKeepingSeparator splits after every separator so that joining the pieces gives back the text
+func splitKeepingSeparator(text, separator string) []string {
+	if separator == "" {
+		return strings.Split(text, "")
+	}
+	return strings.SplitAfter(text, separator)
+}
+
+func splitRunes(text string, size int) []string {
+	runes := []rune(text)
+	chunks := make([]string, 0, len(runes)/size+1)
+	for start := 0; start < len(runes); start += size {
+		end := min(start+size, len(runes))
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks
+}
@@ -0,0 +1,152 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+var _ Tokenizer = (*BPE)(nil)
+
+var ErrInvalidVocabulary = errors.New("invalid vocabulary")
+
+// QwenPattern splits text into the pieces the qwen vocabulary was trained on
+const QwenPattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+// BPE is a byte pair encoding tokenizer on a vocabulary in the tiktoken format,
+// one base64 encoded token and its rank per line, like qwen.tiktoken
+type BPE struct {
+	ranks   map[string]int
+	pattern *regexp2.Regexp
+}
+
+// NewBPEFromFile loads a tiktoken vocabulary and splits texts with the given pattern
+func NewBPEFromFile(path, pattern string) (*BPE, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: line %d", ErrInvalidVocabulary, line)
+		}
+
+		token, err := base64.StdEncoding.DecodeString(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidVocabulary, line, err)
+		}
+		rank, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidVocabulary, line, err)
+		}
+		ranks[string(token)] = rank
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return NewBPE(ranks, pattern)
+}
+
+func NewBPE(ranks map[string]int, pattern string) (*BPE, error) {
+	if len(ranks) == 0 {
+		return nil, ErrInvalidVocabulary
+	}
+
+	compiled, err := regexp2.Compile(pattern, regexp2.Unicode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BPE{
+		ranks:   ranks,
+		pattern: compiled,
+	}, nil
+}
+
+func (b *BPE) CountTokens(text string) int {
+	count := 0
+	for _, piece := range b.split(text) {
+		count += b.countPiece(piece)
+	}
+	return count
+}
+
+// Truncate cuts between pieces, so it may keep a few tokens less than maxTokens
+func (b *BPE) Truncate(text string, maxTokens int) string {
+	count := 0
+	end := 0
+	for _, piece := range b.split(text) {
+		count += b.countPiece(piece)
+		if count > maxTokens {
+			break
+		}
+		end += len(piece)
+	}
+	return text[:end]
+}
+
+// split returns the pieces of text in order, they add up to the whole text
+func (b *BPE) split(text string) []string {
+	var pieces []string
+	runes := []rune(text)
+
+	match, err := b.pattern.FindStringMatch(text)
+	position := 0
+	for err == nil && match != nil {
+		if match.Index > position {
+			pieces = append(pieces, string(runes[position:match.Index]))
+		}
+		pieces = append(pieces, match.String())
+		position = match.Index + match.Length
+		match, err = b.pattern.FindNextMatch(match)
+	}
+	if position < len(runes) {
+		pieces = append(pieces, string(runes[position:]))
+	}
+
+	return pieces
+}
+
+// countPiece merges the lowest ranked adjacent pair until no pair is in the vocabulary
+func (b *BPE) countPiece(piece string) int {
+	if _, exists := b.ranks[piece]; exists {
+		return 1
+	}
+
+	// boundaries[i] is where the i-th part of the piece starts
+	boundaries := make([]int, len(piece)+1)
+	for i := range boundaries {
+		boundaries[i] = i
+	}
+
+	for len(boundaries) > 2 {
+		minRank, minIndex := math.MaxInt, -1
+		for i := 0; i+2 < len(boundaries); i++ {
+			if rank, exists := b.ranks[piece[boundaries[i]:boundaries[i+2]]]; exists && rank < minRank {
+				minRank, minIndex = rank, i
+			}
+		}
+		if minIndex < 0 {
+			break
+		}
+		boundaries = append(boundaries[:minIndex+1], boundaries[minIndex+2:]...)
+	}
+
+	return len(boundaries) - 1
+}
@@ -0,0 +1,45 @@
+package tokenizer
+
+import "unicode"
+
+var _ Tokenizer = Estimator{}
+
+// Estimator guesses the token count without a vocabulary: a token per CJK character
+// and per four other characters, which is close to what qwen models use for most texts
+type Estimator struct{}
+
+func (Estimator) CountTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+func (e Estimator) Truncate(text string, maxTokens int) string {
+	if maxTokens <= 0 {
+		return ""
+	}
+
+	cjk, other := 0, 0
+	for i, r := range text {
+		if isCJK(r) {
+			cjk++
+		} else {
+			other++
+		}
+		if cjk+(other+3)/4 > maxTokens {
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
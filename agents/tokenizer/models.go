@@ -0,0 +1,25 @@
+package tokenizer
+
+import "strings"
+
+const DefaultContextWindow = 8192
+
+// contextWindows are the input limits of the models we call, matched by the longest name prefix
+var contextWindows = map[string]int{
+	"qwen-turbo": 131072,
+	"qwen-plus":  131072,
+	"qwen-max":   32768,
+	"qwen-long":  1000000,
+	"qwen-vl":    32768,
+}
+
+// ContextWindow returns how many tokens the model accepts, or DefaultContextWindow for unknown models
+func ContextWindow(model string) int {
+	window, matched := DefaultContextWindow, ""
+	for prefix, size := range contextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			window, matched = size, prefix
+		}
+	}
+	return window
+}
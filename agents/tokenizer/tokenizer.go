@@ -0,0 +1,8 @@
+// Package tokenizer counts how many tokens a text takes up in a model's context window
+package tokenizer
+
+type Tokenizer interface {
+	CountTokens(text string) int
+	// Truncate returns the longest prefix of text that takes up at most maxTokens
+	Truncate(text string, maxTokens int) string
+}
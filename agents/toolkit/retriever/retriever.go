@@ -0,0 +1,74 @@
+package retriever
+
+import (
+	"context"
+	"easy-chat/agents/toolkit"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var _ toolkit.Tool = (*Tool)(nil)
+
+var ErrMissedRetriever = errors.New("missed retriever")
+
+// Source is a piece of a document returned by a Retriever
+type Source struct {
+	// Reference tells where the content comes from, e.g. the file name and the position in it
+	Reference string
+	Content   string
+	Score     float32
+}
+
+// Retriever finds the pieces of a knowledge base most relevant to a query, best first
+type Retriever interface {
+	Retrieve(ctx context.Context, query string) ([]Source, error)
+}
+
+// Tool lets the agent search a private knowledge base, such as the documents a user uploaded
+type Tool struct {
+	Retriever Retriever
+}
+
+func NewTool(retriever Retriever) (*Tool, error) {
+	if retriever == nil {
+		return nil, ErrMissedRetriever
+	}
+
+	return &Tool{
+		Retriever: retriever,
+	}, nil
+}
+
+func (t *Tool) Name() string {
+	return "Knowledge Base Search"
+}
+
+func (t *Tool) Description() string {
+	return "Search the documents the user uploaded. " +
+		"Use it for questions about the user's own files, notes or internal material before searching the web. " +
+		"The input is a search query in natural language."
+}
+
+func (t *Tool) Execute(ctx context.Context, input string) (string, error) {
+	sources, err := t.Retriever.Retrieve(ctx, strings.TrimSpace(input))
+	if err != nil {
+		return "", err
+	}
+
+	return buildRetrieveResult(sources), nil
+}
+
+func buildRetrieveResult(sources []Source) string {
+	if len(sources) == 0 {
+		return "No relevant documents found."
+	}
+
+	var result strings.Builder
+	for i, source := range sources {
+		result.WriteString(fmt.Sprintf("[%d] Source: %s\n", i+1, source.Reference))
+		result.WriteString("Text: " + source.Content + "\n\n")
+	}
+
+	return result.String()
+}
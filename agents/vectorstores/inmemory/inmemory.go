@@ -0,0 +1,93 @@
+package inmemory
+
+import (
+	"context"
+	"easy-chat/agents/vectorstores"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var _ vectorstores.VectorStore = (*Store)(nil)
+
+// Store keeps every document in memory and searches them exhaustively
+type Store struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]vectorstores.Document
+}
+
+func New() *Store {
+	return &Store{
+		collections: make(map[string]map[string]vectorstores.Document),
+	}
+}
+
+func (s *Store) Upsert(ctx context.Context, collection string, documents []vectorstores.Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.collections[collection]; !exists {
+		s.collections[collection] = make(map[string]vectorstores.Document)
+	}
+
+	for _, document := range documents {
+		s.collections[collection][document.ID] = copyDocument(document)
+	}
+	return nil
+}
+
+func (s *Store) Delete(ctx context.Context, collection string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		delete(s.collections[collection], id)
+	}
+	return nil
+}
+
+func (s *Store) DeleteByMetadata(ctx context.Context, collection string, filter map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, document := range s.collections[collection] {
+		if vectorstores.MatchMetadata(document.Metadata, filter) {
+			delete(s.collections[collection], id)
+		}
+	}
+	return nil
+}
+
+func (s *Store) Search(ctx context.Context, collection string, embedding []float32, options ...vectorstores.SearchOption) ([]vectorstores.SearchResult, error) {
+	opts := vectorstores.GetDefaultSearchOptions()
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	s.mu.RLock()
+	documents := make([]vectorstores.Document, 0, len(s.collections[collection]))
+	for _, document := range s.collections[collection] {
+		documents = append(documents, copyDocument(document))
+	}
+	s.mu.RUnlock()
+
+	// map iteration is random, sort so that equal scores come back in a stable order
+	slices.SortFunc(documents, func(a, b vectorstores.Document) int {
+		switch {
+		case a.ID < b.ID:
+			return -1
+		case a.ID > b.ID:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	return vectorstores.Rank(documents, embedding, opts), nil
+}
+
+func copyDocument(document vectorstores.Document) vectorstores.Document {
+	document.Embedding = slices.Clone(document.Embedding)
+	document.Metadata = maps.Clone(document.Metadata)
+	return document
+}
@@ -0,0 +1,44 @@
+package vectorstores
+
+const defaultTopK = 5
+
+type SearchOptions struct {
+	TopK       int
+	Similarity Similarity
+	Filter     map[string]string
+	// MinScore drops results that score below it
+	MinScore float32
+}
+
+func GetDefaultSearchOptions() *SearchOptions {
+	return &SearchOptions{
+		TopK:       defaultTopK,
+		Similarity: CosineSimilarity,
+	}
+}
+
+type SearchOption func(*SearchOptions)
+
+func WithTopK(topK int) SearchOption {
+	return func(o *SearchOptions) {
+		o.TopK = topK
+	}
+}
+
+func WithSimilarity(similarity Similarity) SearchOption {
+	return func(o *SearchOptions) {
+		o.Similarity = similarity
+	}
+}
+
+func WithFilter(filter map[string]string) SearchOption {
+	return func(o *SearchOptions) {
+		o.Filter = filter
+	}
+}
+
+func WithMinScore(minScore float32) SearchOption {
+	return func(o *SearchOptions) {
+		o.MinScore = minScore
+	}
+}
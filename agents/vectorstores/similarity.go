@@ -0,0 +1,71 @@
+package vectorstores
+
+import (
+	"math"
+	"slices"
+)
+
+// Similarity scores two embeddings, a higher score means more similar.
+// Embeddings of different lengths score zero.
+type Similarity func(a, b []float32) float32
+
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+func DotProductSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return float32(dot)
+}
+
+// Rank scores every document against the embedding and keeps the best TopK that pass the options
+func Rank(documents []Document, embedding []float32, opts *SearchOptions) []SearchResult {
+	results := make([]SearchResult, 0, len(documents))
+	for _, document := range documents {
+		if !MatchMetadata(document.Metadata, opts.Filter) {
+			continue
+		}
+
+		score := opts.Similarity(embedding, document.Embedding)
+		if score < opts.MinScore {
+			continue
+		}
+		results = append(results, SearchResult{Document: document, Score: score})
+	}
+
+	slices.SortStableFunc(results, func(a, b SearchResult) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	if opts.TopK > 0 && len(results) > opts.TopK {
+		results = results[:opts.TopK]
+	}
+	return results
+}
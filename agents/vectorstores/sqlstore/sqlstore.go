@@ -0,0 +1,207 @@
+// Package sqlstore persists vectors in a relational database through gorm,
+// so it works with every driver the dao package supports. Searches are exact:
+// candidate rows are narrowed down in SQL and scored in Go.
+package sqlstore
+
+import (
+	"context"
+	"easy-chat/agents/vectorstores"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ vectorstores.VectorStore = (*Store)(nil)
+
+const searchBatchSize = 1000
+
+// likeEscaper escapes LIKE wildcards with '!', which unlike backslash means the same on every driver
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+type entry struct {
+	Collection string    `gorm:"primaryKey;type:varchar(100)"`
+	DocumentID string    `gorm:"primaryKey;type:varchar(100)"`
+	CreateTime time.Time `gorm:"autoCreateTime"`
+	UpdateTime time.Time `gorm:"autoUpdateTime"`
+	Content    string    `gorm:"type:text"`
+	Metadata   string    `gorm:"type:text"`
+	Embedding  []byte    `gorm:"not null"`
+}
+
+func (entry) TableName() string {
+	return "vector_entry"
+}
+
+type Store struct {
+	db *gorm.DB
+}
+
+// New returns a store on the vector_entry table, which is created by the dao migrations
+func New(db *gorm.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) Upsert(ctx context.Context, collection string, documents []vectorstores.Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	entries := make([]*entry, len(documents))
+	for i, document := range documents {
+		metadata, err := json.Marshal(document.Metadata)
+		if err != nil {
+			return err
+		}
+
+		entries[i] = &entry{
+			Collection: collection,
+			DocumentID: document.ID,
+			Content:    document.Content,
+			Metadata:   string(metadata),
+			Embedding:  EncodeVector(document.Embedding),
+		}
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "collection"}, {Name: "document_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"update_time", "content", "metadata", "embedding"}),
+	}).Create(&entries).Error
+}
+
+func (s *Store) Delete(ctx context.Context, collection string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).
+		Where("collection = ? AND document_id IN ?", collection, ids).
+		Delete(&entry{}).Error
+}
+
+func (s *Store) DeleteByMetadata(ctx context.Context, collection string, filter map[string]string) error {
+	var ids []string
+	err := s.scan(ctx, collection, filter, func(entries []*entry) error {
+		for _, e := range entries {
+			document, err := e.toDocument()
+			if err != nil {
+				return err
+			}
+			if vectorstores.MatchMetadata(document.Metadata, filter) {
+				ids = append(ids, e.DocumentID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(ids); start += searchBatchSize {
+		end := min(start+searchBatchSize, len(ids))
+		if err := s.Delete(ctx, collection, ids[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) Search(ctx context.Context, collection string, embedding []float32, options ...vectorstores.SearchOption) ([]vectorstores.SearchResult, error) {
+	opts := vectorstores.GetDefaultSearchOptions()
+	for _, opt := range options {
+		opt(opts)
+	}
+
+	// keep only the best TopK of every batch so memory stays bounded on large collections
+	var candidates []vectorstores.Document
+	err := s.scan(ctx, collection, opts.Filter, func(entries []*entry) error {
+		documents := make([]vectorstores.Document, 0, len(entries))
+		for _, e := range entries {
+			document, err := e.toDocument()
+			if err != nil {
+				return err
+			}
+			documents = append(documents, document)
+		}
+
+		for _, result := range vectorstores.Rank(documents, embedding, opts) {
+			candidates = append(candidates, result.Document)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return vectorstores.Rank(candidates, embedding, opts), nil
+}
+
+// scan walks the collection in batches ordered by document ID.
+// Rows are narrowed down with LIKE on the JSON encoded metadata: encoding/json writes
+// pairs the same way every time, so this never misses a match, but fn must still check
+// the decoded metadata since a value could contain a lookalike pair.
+func (s *Store) scan(ctx context.Context, collection string, filter map[string]string, fn func(entries []*entry) error) error {
+	var lastID string
+	for {
+		query := s.db.WithContext(ctx).Where("collection = ? AND document_id > ?", collection, lastID)
+		for key, value := range filter {
+			pair, _ := json.Marshal(map[string]string{key: value})
+			fragment := strings.TrimSuffix(strings.TrimPrefix(string(pair), "{"), "}")
+			query = query.Where("metadata LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(fragment)+"%")
+		}
+
+		var entries []*entry
+		if err := query.Order("document_id").Limit(searchBatchSize).Find(&entries).Error; err != nil {
+			return err
+		}
+
+		if len(entries) == 0 {
+			return nil
+		}
+
+		if err := fn(entries); err != nil {
+			return err
+		}
+
+		if len(entries) < searchBatchSize {
+			return nil
+		}
+		lastID = entries[len(entries)-1].DocumentID
+	}
+}
+
+func (e *entry) toDocument() (vectorstores.Document, error) {
+	var metadata map[string]string
+	if e.Metadata != "" {
+		if err := json.Unmarshal([]byte(e.Metadata), &metadata); err != nil {
+			return vectorstores.Document{}, err
+		}
+	}
+
+	return vectorstores.Document{
+		ID:        e.DocumentID,
+		Content:   e.Content,
+		Embedding: DecodeVector(e.Embedding),
+		Metadata:  metadata,
+	}, nil
+}
+
+// EncodeVector stores each component as a little endian float32
+func EncodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, value := range vector {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(value))
+	}
+	return buf
+}
+
+func DecodeVector(buf []byte) []float32 {
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return vector
+}
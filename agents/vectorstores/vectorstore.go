@@ -0,0 +1,37 @@
+package vectorstores
+
+import "context"
+
+// Document is a piece of text stored together with its embedding
+type Document struct {
+	ID        string
+	Content   string
+	Embedding []float32
+	Metadata  map[string]string
+}
+
+type SearchResult struct {
+	Document
+	Score float32
+}
+
+// VectorStore keeps documents in named collections and finds the ones nearest to an embedding
+type VectorStore interface {
+	// Upsert inserts the documents or replaces the ones with the same ID
+	Upsert(ctx context.Context, collection string, documents []Document) error
+	Delete(ctx context.Context, collection string, ids []string) error
+	// DeleteByMetadata removes every document whose metadata contains all the given pairs
+	DeleteByMetadata(ctx context.Context, collection string, filter map[string]string) error
+	// Search returns the most similar documents first
+	Search(ctx context.Context, collection string, embedding []float32, options ...SearchOption) ([]SearchResult, error)
+}
+
+// MatchMetadata reports whether metadata contains all pairs of the filter
+func MatchMetadata(metadata, filter map[string]string) bool {
+	for key, value := range filter {
+		if metadata[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,22 @@
+// Package blobstore keeps uploaded files out of the database
+package blobstore
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	ErrInvalidBlobKey = errors.New("invalid blob key")
+)
+
+// BlobStore keeps blobs by slash-separated keys, e.g. session/file.png
+type BlobStore interface {
+	Put(ctx context.Context, key string, content []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the blob, a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+	// DeleteDir removes every blob whose key starts with dir and a slash
+	DeleteDir(ctx context.Context, dir string) error
+}
@@ -0,0 +1,93 @@
+// Package local keeps blobs as files under a directory of the local filesystem
+package local
+
+import (
+	"context"
+	"easy-chat/blobstore"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var _ blobstore.BlobStore = (*Store)(nil)
+
+type Store struct {
+	root string
+}
+
+// New returns a store under root, which is created on the first write
+func New(root string) *Store {
+	return &Store{root: root}
+}
+
+func (s *Store) Put(ctx context.Context, key string, content []byte) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a failed write never leaves half a blob behind the key
+	file, err := os.CreateTemp(filepath.Dir(name), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), name)
+}
+
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", blobstore.ErrBlobNotFound, key)
+	}
+	return content, err
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) DeleteDir(ctx context.Context, dir string) error {
+	name, err := s.path(dir)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(name)
+}
+
+// path maps the key to a file under the root, keys must not leave it
+func (s *Store) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return "", fmt.Errorf("%w: %q", blobstore.ErrInvalidBlobKey, key)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
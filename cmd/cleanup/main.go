@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"easy-chat/blobstore/local"
+	"easy-chat/config"
+	"easy-chat/dao"
+	"easy-chat/service"
+	"flag"
+	"log"
+)
+
+func main() {
+	configFilePath := flag.String("config", "config-dev.yaml", "path to the config file")
+	dryRun := flag.Bool("dry-run", false, "only report orphaned rows without deleting them")
+	flag.Parse()
+
+	if err := config.Init(*configFilePath); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := dao.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := dao.CleanupOrphans(*dryRun)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	action := "deleted"
+	if *dryRun {
+		action = "found"
+	}
+	log.Printf("%s %d chat_history rows without a session", action, report.ChatHistoryWithoutSession)
+	log.Printf("%s %d chat_history rows without a user", action, report.ChatHistoryWithoutUser)
+	log.Printf("%s %d chat_session rows without a user", action, report.ChatSessionWithoutUser)
+	log.Printf("%s %d chat_history vectors without a message", action, report.VectorWithoutChatHistory)
+	log.Printf("%s %d document rows without a user", action, report.DocumentWithoutUser)
+	log.Printf("%s %d document rows without a session", action, report.DocumentWithoutSession)
+	log.Printf("%s %d document_chunk rows without a document", action, report.DocumentChunkWithoutDocument)
+	log.Printf("%s %d document_chunk vectors without a chunk", action, report.VectorWithoutDocumentChunk)
+	log.Printf("%s %d user_memory rows without a user", action, report.UserMemoryWithoutUser)
+	log.Printf("%s %d user_memory vectors without a memory", action, report.VectorWithoutUserMemory)
+	log.Printf("%s %d assistant rows without a user", action, report.AssistantWithoutUser)
+	log.Printf("%s %d shared_link rows without a user", action, report.SharedLinkWithoutUser)
+	log.Printf("%s %d shared_link rows without a session", action, report.SharedLinkWithoutSession)
+	log.Printf("%s %d message_feedback rows without a message", action, report.FeedbackWithoutMessage)
+	log.Printf("%s %d attachment rows without a user", action, report.AttachmentWithoutUser)
+	log.Printf("%s %d attachment rows without a session", action, report.AttachmentWithoutSession)
+	if *dryRun {
+		log.Printf("found %d files of orphaned attachments", len(report.AttachmentBlobKeys))
+	} else {
+		log.Printf("deleted %d files of orphaned attachments", deleteBlobs(report.AttachmentBlobKeys))
+	}
+	log.Printf("%s %d model_comparison rows without a message", action, report.ComparisonWithoutMessage)
+}
+
+// deleteBlobs removes the files of the deleted attachments and returns how many were removed
+func deleteBlobs(keys []string) int {
+	blobStore := local.New(service.AttachmentDir())
+	deleted := 0
+	for _, key := range keys {
+		if err := blobStore.Delete(context.Background(), key); err != nil {
+			log.Printf("failed to delete attachment file %s: %v", key, err)
+			continue
+		}
+		deleted++
+	}
+	return deleted
+}
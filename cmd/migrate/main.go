@@ -0,0 +1,72 @@
+package main
+
+import (
+	"easy-chat/config"
+	"easy-chat/dao"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+const usage = `usage: migrate [-config path] <up|down|status>
+
+  up      apply every pending migration
+  down    roll back the most recently applied migration
+  status  list migrations and whether they have been applied
+`
+
+func main() {
+	configFilePath := flag.String("config", "config-dev.yaml", "path to the config file")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := config.Init(*configFilePath); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := dao.Init(); err != nil {
+		log.Fatal(err)
+	}
+
+	switch flag.Arg(0) {
+	case "up":
+		if err := dao.MigrateUp(); err != nil {
+			log.Fatal(err)
+		}
+	case "down":
+		if err := dao.MigrateDown(); err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		if err := printStatus(); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printStatus() error {
+	statuses, err := dao.GetMigrationStatuses()
+	if err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		appliedTime := "pending"
+		if status.Applied {
+			appliedTime = status.AppliedTime.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%4d  %-30s  %s\n", status.Version, status.Name, appliedTime)
+	}
+	return nil
+}
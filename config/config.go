@@ -17,11 +17,33 @@ var globalConfig *Config
 
 type Config struct {
 	DataBase struct {
-		Mysql struct {
+		// Driver is one of mysql, postgres or sqlite, defaults to mysql
+		Driver      string `yaml:"driver"`
+		AutoMigrate bool   `yaml:"auto_migrate"`
+		Mysql       struct {
+			Host     string `yaml:"host"`
 			Port     string `yaml:"port"`
 			Username string `yaml:"username"`
 			Password string `yaml:"password"`
+			Name     string `yaml:"name"`
 		} `yaml:"mysql"`
+		Postgres struct {
+			Host     string `yaml:"host"`
+			Port     string `yaml:"port"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+			Name     string `yaml:"name"`
+			SSLMode  string `yaml:"sslmode"`
+		} `yaml:"postgres"`
+		SQLite struct {
+			Path string `yaml:"path"`
+		} `yaml:"sqlite"`
+		Pool struct {
+			MaxOpenConns int `yaml:"max_open_conns"`
+			MaxIdleConns int `yaml:"max_idle_conns"`
+			// ConnMaxLifetime is measured in seconds
+			ConnMaxLifetime int `yaml:"conn_max_lifetime"`
+		} `yaml:"pool"`
 	} `yaml:"database"`
 	SecretKey struct {
 		JWT string `yaml:"jwt"`
@@ -31,7 +53,59 @@ type Config struct {
 		Exa  string `yaml:"exa"`
 	} `yaml:"api_key"`
 	AllowedOrigin []string `yaml:"allowed_origin"`
-	MQ            struct {
+	AllowedModels []string `yaml:"allowed_models"`
+	// Admins are the usernames allowed to read the reports under /api/admin
+	Admins  []string `yaml:"admins"`
+	Session struct {
+		TrashRetentionDays int    `yaml:"trash_retention_days"`
+		TitleModel         string `yaml:"title_model"`
+	} `yaml:"session"`
+	Embedding struct {
+		Model string `yaml:"model"`
+	} `yaml:"embedding"`
+	Tokenizer struct {
+		// VocabFiles maps a model name prefix to a tiktoken vocabulary, e.g. qwen: /data/qwen.tiktoken.
+		// Models without one have their tokens estimated.
+		VocabFiles map[string]string `yaml:"vocab_files"`
+		// ContextWindows overrides the built-in context window of a model
+		ContextWindows map[string]int `yaml:"context_windows"`
+		// ReservedTokens is kept free for the answer, defaults to 2048
+		ReservedTokens int `yaml:"reserved_tokens"`
+	} `yaml:"tokenizer"`
+	Memory struct {
+		// DefaultStrategy is used by sessions that did not pick one, defaults to token_buffer
+		DefaultStrategy string `yaml:"default_strategy"`
+		WindowSize      int    `yaml:"window_size"`
+		MaxTokens       int    `yaml:"max_tokens"`
+		SummaryModel    string `yaml:"summary_model"`
+		RetrievalTopK   int    `yaml:"retrieval_top_k"`
+	} `yaml:"memory"`
+	UserMemory struct {
+		// Model extracts facts from conversations, defaults to qwen-turbo
+		Model string `yaml:"model"`
+		// TopK is how many memories are put into a prompt
+		TopK       int `yaml:"top_k"`
+		MaxPerUser int `yaml:"max_per_user"`
+	} `yaml:"user_memory"`
+	Document struct {
+		// MaxUploadSize is measured in megabytes
+		MaxUploadSize int `yaml:"max_upload_size"`
+		ChunkSize     int `yaml:"chunk_size"`
+		ChunkOverlap  int `yaml:"chunk_overlap"`
+		// RetrievalTopK is how many chunks the rag mode puts into the prompt
+		RetrievalTopK int `yaml:"retrieval_top_k"`
+	} `yaml:"document"`
+	Attachment struct {
+		// MaxUploadSize is measured in megabytes
+		MaxUploadSize int `yaml:"max_upload_size"`
+		// Dir is where the local blob store keeps the files, defaults to data/attachments
+		Dir string `yaml:"dir"`
+		// MaxTextTokens caps the text of each attached file in the prompt
+		MaxTextTokens int `yaml:"max_text_tokens"`
+		// UnsentRetentionHours is how long an upload that was never sent is kept
+		UnsentRetentionHours int `yaml:"unsent_retention_hours"`
+	} `yaml:"attachment"`
+	MQ struct {
 		Port     string `yaml:"port"`
 		Username string `yaml:"username"`
 		Password string `yaml:"password"`
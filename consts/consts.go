@@ -6,10 +6,28 @@ type ContextKey string
 
 var ErrInvalidContextKey = errors.New("invalid context key")
 
-const KeyStreamFunc ContextKey = "stream_func"
+const (
+	KeyStreamFunc ContextKey = "stream_func"
+	KeyEventFunc  ContextKey = "event_func"
+)
+
+// EventFunc sends a named event with a JSON payload to the client
+type EventFunc func(event string, data interface{})
+
+// gin context key
+const KeyUsername = "username"
 
 // sse event
 const (
-	SSEventResult = "result"
-	SSEventError  = "error"
+	SSEventResult    = "result"
+	SSEventError     = "error"
+	SSEventCitations = "citations"
+	// SSEventSessionUpdated carries the session_id and session_name of a session that was just titled
+	SSEventSessionUpdated = "session_updated"
+	// SSEventCompare lists the channels of a comparison, every model streams its answer as result:<channel>
+	// and its failure as error:<channel>
+	SSEventCompare     = "compare"
+	SSEventCompareDone = "compare_done"
+	// SSEventAttachmentsTruncated lists the attached files whose text was cut to fit the prompt
+	SSEventAttachmentsTruncated = "attachments_truncated"
 )
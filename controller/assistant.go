@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type assistantResponse struct {
+	ID           uint      `json:"id"`
+	Name         string    `json:"name"`
+	SystemPrompt string    `json:"system_prompt"`
+	CreateTime   time.Time `json:"create_time"`
+	UpdateTime   time.Time `json:"update_time"`
+	chatSettingsResponse
+}
+
+type chatSettingsResponse struct {
+	Model       string   `json:"model"`
+	Mode        string   `json:"mode"`
+	Temperature *float64 `json:"temperature"`
+	TopP        *float64 `json:"top_p"`
+	MaxTokens   int      `json:"max_tokens"`
+	Tools       []string `json:"tools"`
+}
+
+func CreateAssistantAPI(c *gin.Context) {
+	var req request.AssistantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	assistant, err := service.CreateAssistant(ctx, username, &req)
+	if err != nil {
+		respondAssistantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildAssistantResponse(assistant))
+}
+
+func GetAssistantsAPI(c *gin.Context) {
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	assistants, err := service.ListAssistants(ctx, username)
+	if err != nil {
+		respondAssistantError(c, err)
+		return
+	}
+
+	response := make([]assistantResponse, len(assistants))
+	for i, assistant := range assistants {
+		response[i] = buildAssistantResponse(assistant)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func GetAssistantAPI(c *gin.Context) {
+	assistantID, err := parseUintParam(c, "assistant_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	assistant, err := service.GetAssistant(ctx, username, assistantID)
+	if err != nil {
+		respondAssistantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, buildAssistantResponse(assistant))
+}
+
+func UpdateAssistantAPI(c *gin.Context) {
+	assistantID, err := parseUintParam(c, "assistant_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req request.AssistantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	assistant, err := service.UpdateAssistant(ctx, username, assistantID, &req)
+	if err != nil {
+		respondAssistantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, buildAssistantResponse(assistant))
+}
+
+func DeleteAssistantAPI(c *gin.Context) {
+	assistantID, err := parseUintParam(c, "assistant_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.DeleteAssistant(ctx, username, assistantID); err != nil {
+		respondAssistantError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "assistant deleted successfully"})
+}
+
+func buildAssistantResponse(assistant *entity.Assistant) assistantResponse {
+	return assistantResponse{
+		ID:                   assistant.ID,
+		Name:                 assistant.Name,
+		SystemPrompt:         assistant.SystemPrompt,
+		CreateTime:           assistant.CreateTime,
+		UpdateTime:           assistant.UpdateTime,
+		chatSettingsResponse: buildChatSettingsResponse(assistant.ChatSettings),
+	}
+}
+
+func buildChatSettingsResponse(settings entity.ChatSettings) chatSettingsResponse {
+	return chatSettingsResponse{
+		Model:       settings.Model,
+		Mode:        settings.Mode,
+		Temperature: settings.Temperature,
+		TopP:        settings.TopP,
+		MaxTokens:   settings.MaxTokens,
+		Tools:       service.SplitTools(settings.Tools),
+	}
+}
+
+func respondAssistantError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAssistantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,133 @@
+package controller
+
+import (
+	"easy-chat/agents/documentloaders"
+	"easy-chat/blobstore"
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type attachmentResponse struct {
+	ID          uint      `json:"id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Kind        string    `json:"kind"`
+	Size        int64     `json:"size"`
+	URL         string    `json:"url"`
+	CreateTime  time.Time `json:"create_time"`
+}
+
+// UploadAttachmentAPI keeps a file for the session, its ID is then sent with a query
+func UploadAttachmentAPI(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxAttachmentSize()+1<<20)
+
+	var req request.AttachmentUploadRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	if req.File.Size > service.MaxAttachmentSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrAttachmentTooLarge.Error()})
+		return
+	}
+
+	file, err := req.File.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	attachment, err := service.UploadAttachment(ctx, username, c.Param("session_id"), req.File.Filename, content)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildAttachmentResponse(attachment))
+}
+
+// GetAttachmentContentAPI serves the file of an attachment with the type it was detected as
+func GetAttachmentContentAPI(c *gin.Context) {
+	attachmentID, err := parseUintParam(c, "attachment_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	attachment, content, err := service.GetAttachmentContent(ctx, username, attachmentID)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%s", strconv.Quote(attachment.FileName)))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, attachment.ContentType, content)
+}
+
+func DeleteAttachmentAPI(c *gin.Context) {
+	attachmentID, err := parseUintParam(c, "attachment_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.DeleteAttachment(ctx, username, attachmentID); err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "attachment deleted successfully"})
+}
+
+func buildAttachmentResponse(attachment *entity.Attachment) attachmentResponse {
+	return attachmentResponse{
+		ID:          attachment.ID,
+		FileName:    attachment.FileName,
+		ContentType: attachment.ContentType,
+		Kind:        attachment.Kind,
+		Size:        attachment.Size,
+		URL:         fmt.Sprintf("/api/attachments/%d/content", attachment.ID),
+		CreateTime:  attachment.CreateTime,
+	}
+}
+
+func respondAttachmentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrAttachmentNotFound), errors.Is(err, service.ErrSessionNotFound),
+		errors.Is(err, blobstore.ErrBlobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedAttachment), errors.Is(err, documentloaders.ErrUnsupportedFileType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAttachmentAlreadySent):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -12,12 +12,18 @@ func ChatAPI(c *gin.Context) {
 
 	var req request.ChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.SSEvent(consts.SSEventError, err.Error())
+		c.SSEvent(consts.SSEventError, buildBindErrorResponse(err))
 		c.Writer.Flush()
 		return
 	}
+	req.Username = c.GetString(consts.KeyUsername)
+
+	streamChatRequest(c, &req)
+}
 
-	if err := mq.PublishChatRequest(c, &req); err != nil {
+// streamChatRequest hands the request to a chat consumer and waits until the answer has been streamed
+func streamChatRequest(c *gin.Context, req *request.ChatRequest) {
+	if err := mq.PublishChatRequest(c, req); err != nil {
 		c.SSEvent(consts.SSEventError, err.Error())
 		c.Writer.Flush()
 		return
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegenerateMessageAPI streams a new answer to a user message, or to the question of an answer.
+// The body may override the session's settings for it.
+func RegenerateMessageAPI(c *gin.Context) {
+	messageID, err := parseUintParam(c, "message_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var settings request.ChatSettings
+	if err := c.ShouldBindJSON(&settings); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	req, err := service.NewRegenerateRequest(ctx, username, c.Param("session_id"), messageID)
+	if err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+	req.ChatSettings = settings
+
+	setHeaders(c)
+	streamChatRequest(c, req)
+}
+
+// EditMessageAPI streams the answer to an edited user message, which starts a branch next to the original
+func EditMessageAPI(c *gin.Context) {
+	messageID, err := parseUintParam(c, "message_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var body request.MessageEditRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	req, err := service.NewEditRequest(ctx, username, c.Param("session_id"), messageID, body.Query)
+	if err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+	req.AttachmentIDs = body.AttachmentIDs
+	req.ChatSettings = body.ChatSettings
+
+	setHeaders(c)
+	streamChatRequest(c, req)
+}
+
+// ActivateMessageAPI switches the session to the branch through the message
+func ActivateMessageAPI(c *gin.Context) {
+	messageID, err := parseUintParam(c, "message_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.SwitchBranch(ctx, username, c.Param("session_id"), messageID); err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "branch switched successfully"})
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompareChatAPI streams the answers of several models to the query side by side, each on its own channel
+func CompareChatAPI(c *gin.Context) {
+	setHeaders(c)
+
+	var body request.ChatCompareRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.SSEvent(consts.SSEventError, buildBindErrorResponse(err))
+		c.Writer.Flush()
+		return
+	}
+
+	req := &request.ChatRequest{
+		Username:      c.GetString(consts.KeyUsername),
+		SessionID:     body.SessionID,
+		Query:         body.Query,
+		ParentID:      body.ParentID,
+		AttachmentIDs: body.AttachmentIDs,
+		CompareModels: body.Models,
+		ChatSettings: request.ChatSettings{
+			Mode:        service.ModeCompare,
+			Temperature: body.Temperature,
+			TopP:        body.TopP,
+			MaxTokens:   body.MaxTokens,
+		},
+	}
+
+	streamChatRequest(c, req)
+}
+
+// PreferAnswerAPI records which answer of a comparison the user preferred, it becomes the active branch
+func PreferAnswerAPI(c *gin.Context) {
+	messageID, err := parseUintParam(c, "message_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.PreferAnswer(ctx, username, c.Param("session_id"), messageID); err != nil {
+		respondComparisonError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "preference saved successfully"})
+}
+
+// GetComparisonReportAPI reports how often each model won the comparisons, only admins may read it
+func GetComparisonReportAPI(c *gin.Context) {
+	report, err := service.GetComparisonReport(c.Request.Context())
+	if err != nil {
+		respondComparisonError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func respondComparisonError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSessionNotFound), errors.Is(err, service.ErrMessageNotFound),
+		errors.Is(err, service.ErrComparisonNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidComparison):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,98 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+var exportContentTypes = map[string]string{
+	service.ExportFormatMarkdown: "text/markdown; charset=utf-8",
+	service.ExportFormatJSON:     "application/json; charset=utf-8",
+	service.ExportFormatHTML:     "text/html; charset=utf-8",
+}
+
+// ExportChatSessionAPI downloads the session's active branch as Markdown, JSON or HTML, Markdown by default
+func ExportChatSessionAPI(c *gin.Context) {
+	sessionID := c.Param("session_id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "miss parameter 'session_id'"})
+		return
+	}
+
+	var req request.ChatExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+	if req.Format == "" {
+		req.Format = service.ExportFormatMarkdown
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	content, err := service.ExportChatSession(ctx, username, sessionID, req.Format, req.Trace)
+	if err != nil {
+		respondChatExportError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, sessionID, req.Format))
+	c.Data(http.StatusOK, exportContentTypes[req.Format], content)
+}
+
+// ImportChatSessionAPI recreates sessions from an uploaded export
+func ImportChatSessionAPI(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxImportSize()+1<<20)
+
+	var req request.ChatImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	if req.File.Size > service.MaxImportSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import file too large"})
+		return
+	}
+
+	file, err := req.File.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	sessionIDs, err := service.ImportChatSessions(ctx, username, content)
+	if err != nil {
+		respondChatExportError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"session_ids": sessionIDs})
+}
+
+func respondChatExportError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidExportFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidImportFile):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		respondChatSessionError(c, err)
+	}
+}
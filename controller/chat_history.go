@@ -1,12 +1,17 @@
 package controller
 
 import (
+	"easy-chat/consts"
 	"easy-chat/dao"
+	"easy-chat/service"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// GetChatHistoryAPI returns the session's active branch, every message lists its siblings to switch to
 func GetChatHistoryAPI(c *gin.Context) {
 	sessionID := c.Param("session_id")
 	if sessionID == "" {
@@ -14,21 +19,47 @@ func GetChatHistoryAPI(c *gin.Context) {
 		return
 	}
 
-	chatHistories, err := dao.GetChatHistoryBySessionID(sessionID)
+	page, err := parseHistoryPage(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var response = make([]struct {
-		MessageType string `json:"message_type"`
-		Content     string `json:"content"`
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	chatHistories, hasMore, err := service.GetActiveBranch(ctx, username, sessionID, page)
+	if err != nil {
+		if errors.Is(err, dao.ErrCursorNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		respondChatSessionError(c, err)
+		return
+	}
+
+	var messages = make([]struct {
+		ID          uint                 `json:"id"`
+		ParentID    uint                 `json:"parent_id"`
+		SiblingIDs  []uint               `json:"sibling_ids"`
+		MessageType string               `json:"message_type"`
+		Content     string               `json:"content"`
+		Model       string               `json:"model,omitempty"`
+		Attachments []attachmentResponse `json:"attachments,omitempty"`
+		CreateTime  time.Time            `json:"create_time"`
 	}, len(chatHistories))
 
 	for i := 0; i < len(chatHistories); i++ {
-		response[i].MessageType = chatHistories[i].MessageType
-		response[i].Content = chatHistories[i].Content
+		messages[i].ID = chatHistories[i].ID
+		messages[i].ParentID = chatHistories[i].ParentID
+		messages[i].SiblingIDs = chatHistories[i].SiblingIDs
+		messages[i].MessageType = chatHistories[i].MessageType
+		messages[i].Content = chatHistories[i].Content
+		messages[i].Model = chatHistories[i].Model
+		messages[i].CreateTime = chatHistories[i].CreateTime
+		for _, attachment := range chatHistories[i].Attachments {
+			messages[i].Attachments = append(messages[i].Attachments, buildAttachmentResponse(attachment))
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{"messages": messages, "has_more": hasMore})
 }
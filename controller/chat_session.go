@@ -1,23 +1,29 @@
 package controller
 
 import (
+	"easy-chat/consts"
 	"easy-chat/dao"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"time"
 )
 
 func CreateChatSessionAPI(c *gin.Context) {
-	var req struct {
-		Username string `json:"username" binding:"required"`
-	}
+	var req request.ChatSessionCreateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
 		return
 	}
 
-	sessionID, err := dao.CreateChatSession(req.Username)
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	sessionID, err := service.CreateChatSession(ctx, username, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondChatSessionError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{"session_id": sessionID})
@@ -30,36 +36,198 @@ func DeleteChatSessionAPI(c *gin.Context) {
 		return
 	}
 
-	if err := dao.DeleteChatSession(sessionID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	permanent := c.Query("permanent") == "true"
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.DeleteChatSession(ctx, username, sessionID, permanent); err != nil {
+		respondChatSessionError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "chat session deleted successfully"})
+	if permanent {
+		c.JSON(http.StatusOK, gin.H{"message": "chat session deleted successfully"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "chat session moved to trash"})
 }
 
-func GetUserChatSessionAPI(c *gin.Context) {
-	username := c.Param("username")
-	if username == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "miss parameter 'username'"})
+func UpdateChatSessionAPI(c *gin.Context) {
+	sessionID := c.Param("session_id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "miss parameter 'session_id'"})
+		return
+	}
+
+	var req request.ChatSessionUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
 		return
 	}
 
-	sessions, err := dao.GetChatSessionByUsername(username)
+	if req.SessionName == nil && req.MemoryStrategy == nil && req.AssistantID == nil && req.Settings == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	response := gin.H{"session_id": sessionID}
+
+	if req.SessionName != nil {
+		if err := service.RenameChatSession(ctx, username, sessionID, *req.SessionName); err != nil {
+			respondChatSessionError(c, err)
+			return
+		}
+		response["session_name"] = *req.SessionName
+	}
+
+	if req.MemoryStrategy != nil {
+		if err := service.SetMemoryStrategy(ctx, username, sessionID, *req.MemoryStrategy); err != nil {
+			respondChatSessionError(c, err)
+			return
+		}
+		response["memory_strategy"] = *req.MemoryStrategy
+	}
+
+	if req.AssistantID != nil {
+		if err := service.SetChatSessionAssistant(ctx, username, sessionID, *req.AssistantID); err != nil {
+			respondChatSessionError(c, err)
+			return
+		}
+		response["assistant_id"] = *req.AssistantID
+	}
+
+	if req.Settings != nil {
+		if err := service.UpdateChatSessionSettings(ctx, username, sessionID, req.Settings); err != nil {
+			respondChatSessionError(c, err)
+			return
+		}
+		response["settings"] = req.Settings
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func ArchiveChatSessionAPI(c *gin.Context) {
+	setChatSessionArchived(c, true)
+}
+
+func UnarchiveChatSessionAPI(c *gin.Context) {
+	setChatSessionArchived(c, false)
+}
+
+func RestoreChatSessionAPI(c *gin.Context) {
+	sessionID := c.Param("session_id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "miss parameter 'session_id'"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.RestoreChatSession(ctx, username, sessionID); err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "chat session restored successfully"})
+}
+
+// GetChatSessionsAPI lists the caller's sessions with the status of the query, active ones by default
+func GetChatSessionsAPI(c *gin.Context) {
+	page, err := parseSessionPage(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	sessions, hasMore, err := service.GetChatSessions(ctx, username, c.Query("status"), page)
 	if err != nil {
+		if errors.Is(err, dao.ErrInvalidSessionStatus) || errors.Is(err, dao.ErrCursorNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	response := make([]struct {
-		SessionID   string `json:"session_id"`
-		SessionName string `json:"session_name"`
+		SessionID      string               `json:"session_id"`
+		SessionName    string               `json:"session_name"`
+		CreateTime     time.Time            `json:"create_time"`
+		LastActiveTime time.Time            `json:"last_active_time"`
+		MemoryStrategy string               `json:"memory_strategy"`
+		AssistantID    uint                 `json:"assistant_id,omitempty"`
+		Settings       chatSettingsResponse `json:"settings"`
+		ArchiveTime    *time.Time           `json:"archive_time,omitempty"`
+		DeleteTime     *time.Time           `json:"delete_time,omitempty"`
+		PurgeTime      *time.Time           `json:"purge_time,omitempty"`
 	}, len(sessions))
 
 	for i := 0; i < len(sessions); i++ {
 		response[i].SessionID = sessions[i].SessionID
 		response[i].SessionName = sessions[i].SessionName
+		response[i].CreateTime = sessions[i].CreateTime
+		response[i].LastActiveTime = sessions[i].LastActiveTime
+		response[i].MemoryStrategy = service.GetMemoryStrategy(sessions[i])
+		response[i].AssistantID = sessions[i].AssistantID
+		response[i].Settings = buildChatSettingsResponse(sessions[i].ChatSettings)
+		response[i].ArchiveTime = sessions[i].ArchiveTime
+		if sessions[i].DeleteTime.Valid {
+			purgeTime := service.GetTrashPurgeTime(sessions[i])
+			response[i].DeleteTime = &sessions[i].DeleteTime.Time
+			response[i].PurgeTime = &purgeTime
+		}
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{"sessions": response, "has_more": hasMore})
+}
+
+// GetUserChatSessionsAPI keeps the former listing path working, which named the user in the path.
+// It lists like GetChatSessionsAPI and refuses any user but the caller.
+func GetUserChatSessionsAPI(c *gin.Context) {
+	// the wildcard holds the username, see the router
+	if c.Param("session_id") != c.GetString(consts.KeyUsername) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only your own sessions can be listed"})
+		return
+	}
+	GetChatSessionsAPI(c)
+}
+
+func setChatSessionArchived(c *gin.Context, archived bool) {
+	sessionID := c.Param("session_id")
+	if sessionID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "miss parameter 'session_id'"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.ArchiveChatSession(ctx, username, sessionID, archived); err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+
+	if archived {
+		c.JSON(http.StatusOK, gin.H{"message": "chat session archived successfully"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "chat session unarchived successfully"})
+}
+
+func respondChatSessionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSessionNotFound), errors.Is(err, service.ErrAssistantNotFound),
+		errors.Is(err, service.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidMemoryStrategy), errors.Is(err, service.ErrNotUserMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSessionNotTrashed), errors.Is(err, service.ErrSessionTrashExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
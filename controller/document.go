@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"easy-chat/agents/documentloaders"
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type documentResponse struct {
+	ID         uint      `json:"id"`
+	SessionID  string    `json:"session_id,omitempty"`
+	FileName   string    `json:"file_name"`
+	Size       int64     `json:"size"`
+	ChunkCount int       `json:"chunk_count"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+func UploadDocumentAPI(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxUploadSize()+1<<20)
+
+	var req request.DocumentUploadRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	if req.File.Size > service.MaxUploadSize() {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrDocumentTooLarge.Error()})
+		return
+	}
+
+	file, err := req.File.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	document, err := service.UploadDocument(ctx, username, req.SessionID, req.File.Filename, content)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildDocumentResponse(document))
+}
+
+func GetDocumentsAPI(c *gin.Context) {
+	var req request.DocumentListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	documents, err := service.ListDocuments(ctx, username, req.SessionID)
+	if err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	response := make([]documentResponse, len(documents))
+	for i, document := range documents {
+		response[i] = buildDocumentResponse(document)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func DeleteDocumentAPI(c *gin.Context) {
+	documentID, err := parseUintParam(c, "document_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.DeleteDocument(ctx, username, documentID); err != nil {
+		respondDocumentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "document deleted successfully"})
+}
+
+func buildDocumentResponse(document *entity.Document) documentResponse {
+	return documentResponse{
+		ID:         document.ID,
+		SessionID:  document.SessionID,
+		FileName:   document.FileName,
+		Size:       document.Size,
+		ChunkCount: document.ChunkCount,
+		CreateTime: document.CreateTime,
+	}
+}
+
+func respondDocumentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrDocumentNotFound), errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDocumentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, documentloaders.ErrUnsupportedFileType),
+		errors.Is(err, documentloaders.ErrEmptyDocument),
+		errors.Is(err, documentloaders.ErrInvalidEncoding):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,108 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type feedbackResponse struct {
+	MessageID  uint      `json:"message_id"`
+	Rating     string    `json:"rating"`
+	Category   string    `json:"category,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	Model      string    `json:"model"`
+	Mode       string    `json:"mode"`
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// RateMessageAPI rates an answer up or down, rating it again replaces the rating
+func RateMessageAPI(c *gin.Context) {
+	messageID, err := parseUintParam(c, "message_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req request.MessageFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	feedback, err := service.RateMessage(ctx, username, c.Param("session_id"), messageID, &req)
+	if err != nil {
+		respondFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, buildFeedbackResponse(feedback))
+}
+
+func DeleteMessageFeedbackAPI(c *gin.Context) {
+	messageID, err := parseUintParam(c, "message_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.DeleteMessageFeedback(ctx, username, c.Param("session_id"), messageID); err != nil {
+		respondFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "feedback deleted successfully"})
+}
+
+// GetFeedbackReportAPI reports the satisfaction with the answers, only admins may read it
+func GetFeedbackReportAPI(c *gin.Context) {
+	var req request.FeedbackReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	report, err := service.GetFeedbackReport(c.Request.Context(), &req)
+	if err != nil {
+		respondFeedbackError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func buildFeedbackResponse(feedback *entity.MessageFeedback) feedbackResponse {
+	return feedbackResponse{
+		MessageID:  feedback.MessageID,
+		Rating:     feedback.Rating,
+		Category:   feedback.Category,
+		Comment:    feedback.Comment,
+		Model:      feedback.Model,
+		Mode:       feedback.Mode,
+		CreateTime: feedback.CreateTime,
+		UpdateTime: feedback.UpdateTime,
+	}
+}
+
+func respondFeedbackError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSessionNotFound), errors.Is(err, service.ErrMessageNotFound),
+		errors.Is(err, service.ErrFeedbackNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotAIMessage), errors.Is(err, service.ErrInvalidReportRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"easy-chat/dao"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func parseHistoryPage(c *gin.Context) (dao.HistoryPage, error) {
+	var page dao.HistoryPage
+	var err error
+
+	if page.BeforeID, err = parseUintQuery(c, "before"); err != nil {
+		return page, err
+	}
+	if page.AfterID, err = parseUintQuery(c, "after"); err != nil {
+		return page, err
+	}
+	if page.BeforeID != 0 && page.AfterID != 0 {
+		return page, fmt.Errorf("parameters 'before' and 'after' can not be used together")
+	}
+
+	limit, err := parseUintQuery(c, "limit")
+	page.Limit = int(limit)
+	return page, err
+}
+
+func parseSessionPage(c *gin.Context) (dao.SessionPage, error) {
+	limit, err := parseUintQuery(c, "limit")
+	return dao.SessionPage{
+		BeforeID: c.Query("before"),
+		Limit:    int(limit),
+	}, err
+}
+
+func parseUintQuery(c *gin.Context, key string) (uint, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid parameter '%s': %s", key, value)
+	}
+	return uint(parsed), nil
+}
+
+func parseUintParam(c *gin.Context, key string) (uint, error) {
+	value := c.Param(key)
+	parsed, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid parameter '%s': %s", key, value)
+	}
+	return uint(parsed), nil
+}
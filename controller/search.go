@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SearchAPI(c *gin.Context) {
+	var req request.SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	hits, err := service.SearchChatHistory(ctx, username, &req)
+	if errors.Is(err, service.ErrEmptySearchQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]struct {
+		MessageID   uint      `json:"message_id"`
+		SessionID   string    `json:"session_id"`
+		SessionName string    `json:"session_name"`
+		MessageType string    `json:"message_type"`
+		Snippet     string    `json:"snippet"`
+		Highlights  [][2]int  `json:"highlights"`
+		CreateTime  time.Time `json:"create_time"`
+	}, len(hits))
+
+	for i, hit := range hits {
+		response[i].MessageID = hit.MessageID
+		response[i].SessionID = hit.SessionID
+		response[i].SessionName = hit.SessionName
+		response[i].MessageType = hit.MessageType
+		response[i].Snippet = hit.Snippet
+		response[i].Highlights = hit.Highlights
+		response[i].CreateTime = hit.CreateTime
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func SemanticSearchAPI(c *gin.Context) {
+	var req request.SemanticSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	hits, err := service.SemanticSearchChatHistory(ctx, username, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]struct {
+		MessageID   uint      `json:"message_id"`
+		SessionID   string    `json:"session_id"`
+		SessionName string    `json:"session_name"`
+		MessageType string    `json:"message_type"`
+		Content     string    `json:"content"`
+		Score       float32   `json:"score"`
+		CreateTime  time.Time `json:"create_time"`
+	}, len(hits))
+
+	for i, hit := range hits {
+		response[i].MessageID = hit.MessageID
+		response[i].SessionID = hit.SessionID
+		response[i].SessionName = hit.SessionName
+		response[i].MessageType = hit.MessageType
+		response[i].Content = hit.Content
+		response[i].Score = hit.Score
+		response[i].CreateTime = hit.CreateTime
+	}
+
+	c.JSON(http.StatusOK, response)
+}
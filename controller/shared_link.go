@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sharedLinkResponse struct {
+	ID         uint       `json:"id"`
+	SessionID  string     `json:"session_id"`
+	Token      string     `json:"token"`
+	URL        string     `json:"url"`
+	Title      string     `json:"title"`
+	Scope      string     `json:"scope"`
+	CreateTime time.Time  `json:"create_time"`
+	ExpireTime *time.Time `json:"expire_time,omitempty"`
+}
+
+// CreateSharedLinkAPI shares the session's active branch unless the optional body asks otherwise
+func CreateSharedLinkAPI(c *gin.Context) {
+	var req request.SharedLinkCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	sharedLink, err := service.CreateSharedLink(ctx, username, c.Param("session_id"), &req)
+	if err != nil {
+		respondSharedLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildSharedLinkResponse(sharedLink))
+}
+
+func GetSharedLinksAPI(c *gin.Context) {
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	sharedLinks, err := service.ListSharedLinks(ctx, username)
+	if err != nil {
+		respondSharedLinkError(c, err)
+		return
+	}
+
+	response := make([]sharedLinkResponse, len(sharedLinks))
+	for i, sharedLink := range sharedLinks {
+		response[i] = buildSharedLinkResponse(sharedLink)
+	}
+	c.JSON(http.StatusOK, gin.H{"shared_links": response})
+}
+
+func RevokeSharedLinkAPI(c *gin.Context) {
+	sharedLinkID, err := parseUintParam(c, "link_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.RevokeSharedLink(ctx, username, sharedLinkID); err != nil {
+		respondSharedLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "shared link revoked successfully"})
+}
+
+// GetSharedConversationAPI serves a shared link to anyone, it is registered outside of the authentication
+func GetSharedConversationAPI(c *gin.Context) {
+	ctx := c.Request.Context()
+	conversation, err := service.GetSharedConversation(ctx, c.Param("token"))
+	if err != nil {
+		respondSharedLinkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, conversation)
+}
+
+func buildSharedLinkResponse(sharedLink *entity.SharedLink) sharedLinkResponse {
+	return sharedLinkResponse{
+		ID:         sharedLink.ID,
+		SessionID:  sharedLink.SessionID,
+		Token:      sharedLink.Token,
+		URL:        "/api/shared/" + sharedLink.Token,
+		Title:      sharedLink.Title,
+		Scope:      sharedLink.Scope,
+		CreateTime: sharedLink.CreateTime,
+		ExpireTime: sharedLink.ExpireTime,
+	}
+}
+
+func respondSharedLinkError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSharedLinkNotFound), errors.Is(err, service.ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSharedLinkExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNothingToShare):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -1,9 +1,13 @@
 package controller
 
 import (
+	"easy-chat/consts"
 	"easy-chat/dao"
+	"easy-chat/entity"
 	"easy-chat/request"
 	"easy-chat/service"
+	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"net/http"
 )
@@ -11,12 +15,16 @@ import (
 func UserRegisterAPI(c *gin.Context) {
 	var req request.UserRegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
 		return
 	}
 
-	user, err := dao.CreateUser(&req)
+	user, err := service.RegisterUser(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, dao.ErrUsernameAlreadyExists) || errors.Is(err, dao.ErrEmailAlreadyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -27,7 +35,7 @@ func UserRegisterAPI(c *gin.Context) {
 func UserLoginAPI(c *gin.Context) {
 	var req request.UserLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
 		return
 	}
 
@@ -40,3 +48,89 @@ func UserLoginAPI(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"token": token})
 }
+
+func GetUserProfileAPI(c *gin.Context) {
+	username := c.GetString(consts.KeyUsername)
+
+	user, err := service.GetUserProfile(c.Request.Context(), username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, buildUserProfileResponse(user))
+}
+
+func UpdateUserProfileAPI(c *gin.Context) {
+	var req request.UserUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	user, err := service.UpdateUserProfile(ctx, username, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, dao.ErrEmailAlreadyExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidPreferences):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, buildUserProfileResponse(user))
+}
+
+func ChangePasswordAPI(c *gin.Context) {
+	var req request.PasswordChangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.ChangePassword(ctx, username, &req); err != nil {
+		if errors.Is(err, service.ErrInvalidPassword) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "password changed successfully"})
+}
+
+func DeleteUserAPI(c *gin.Context) {
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.DeleteUser(ctx, username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user deleted successfully"})
+}
+
+func buildUserProfileResponse(user *entity.User) gin.H {
+	var preferences json.RawMessage
+	if user.Preferences != "" {
+		preferences = json.RawMessage(user.Preferences)
+	}
+
+	return gin.H{
+		"username":       user.Username,
+		"email":          user.Email,
+		"display_name":   user.DisplayName,
+		"preferences":    preferences,
+		"memory_enabled": user.MemoryEnabled,
+		"create_time":    user.CreateTime,
+		"last_login":     user.LastLogin,
+	}
+}
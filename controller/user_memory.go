@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"easy-chat/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type userMemoryResponse struct {
+	ID         uint      `json:"id"`
+	Content    string    `json:"content"`
+	SessionID  string    `json:"session_id,omitempty"`
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+func GetUserMemoriesAPI(c *gin.Context) {
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	memories, err := service.ListUserMemories(ctx, username)
+	if err != nil {
+		respondUserMemoryError(c, err)
+		return
+	}
+
+	response := make([]userMemoryResponse, len(memories))
+	for i, memory := range memories {
+		response[i] = buildUserMemoryResponse(memory)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func AddUserMemoryAPI(c *gin.Context) {
+	var req request.UserMemoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	memory, err := service.AddUserMemory(ctx, username, req.Content)
+	if err != nil {
+		respondUserMemoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, buildUserMemoryResponse(memory))
+}
+
+func UpdateUserMemoryAPI(c *gin.Context) {
+	memoryID, err := parseUintParam(c, "memory_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req request.UserMemoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, buildBindErrorResponse(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	memory, err := service.UpdateUserMemory(ctx, username, memoryID, req.Content)
+	if err != nil {
+		respondUserMemoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, buildUserMemoryResponse(memory))
+}
+
+func DeleteUserMemoryAPI(c *gin.Context) {
+	memoryID, err := parseUintParam(c, "memory_id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.DeleteUserMemory(ctx, username, memoryID); err != nil {
+		respondUserMemoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "memory deleted successfully"})
+}
+
+func ClearUserMemoriesAPI(c *gin.Context) {
+	ctx := c.Request.Context()
+	username := c.GetString(consts.KeyUsername)
+	if err := service.ClearUserMemories(ctx, username); err != nil {
+		respondUserMemoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "memories cleared successfully"})
+}
+
+func buildUserMemoryResponse(memory *entity.UserMemory) userMemoryResponse {
+	return userMemoryResponse{
+		ID:         memory.ID,
+		Content:    memory.Content,
+		SessionID:  memory.SessionID,
+		CreateTime: memory.CreateTime,
+		UpdateTime: memory.UpdateTime,
+	}
+}
+
+func respondUserMemoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserMemoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrTooManyUserMemories):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
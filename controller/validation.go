@@ -0,0 +1,14 @@
+package controller
+
+import (
+	"easy-chat/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+func buildBindErrorResponse(err error) gin.H {
+	if fields := validation.FieldErrors(err); fields != nil {
+		return gin.H{"error": "validation failed", "fields": fields}
+	}
+	return gin.H{"error": err.Error()}
+}
@@ -0,0 +1,45 @@
+package dao
+
+import (
+	"easy-chat/entity"
+
+	"gorm.io/gorm"
+)
+
+func CreateAssistant(assistant *entity.Assistant) error {
+	return db.Create(assistant).Error
+}
+
+func GetAssistantByID(assistantID uint) (*entity.Assistant, error) {
+	var assistant entity.Assistant
+	if err := db.First(&assistant, assistantID).Error; err != nil {
+		return nil, err
+	}
+	return &assistant, nil
+}
+
+// GetAssistantsByUserID returns the user's assistants in the order they were created
+func GetAssistantsByUserID(userID uint) ([]*entity.Assistant, error) {
+	var assistants []*entity.Assistant
+	if err := db.Where("user_id = ?", userID).Order("id").Find(&assistants).Error; err != nil {
+		return nil, err
+	}
+	return assistants, nil
+}
+
+// UpdateAssistant replaces every setting of the assistant, including the ones cleared to zero values
+func UpdateAssistant(assistant *entity.Assistant) error {
+	return db.Model(assistant).
+		Select(append([]string{"Name", "SystemPrompt"}, chatSettingsFields...)).
+		Updates(assistant).Error
+}
+
+// DeleteAssistant also unbinds the sessions that used the assistant
+func DeleteAssistant(assistantID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&entity.ChatSession{}).Where("assistant_id = ?", assistantID).Update("assistant_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.Assistant{}, assistantID).Error
+	})
+}
@@ -0,0 +1,53 @@
+package dao
+
+import (
+	"easy-chat/entity"
+	"time"
+)
+
+func CreateAttachment(attachment *entity.Attachment) error {
+	return db.Create(attachment).Error
+}
+
+func GetAttachmentsByIDs(ids []uint) ([]*entity.Attachment, error) {
+	var attachments []*entity.Attachment
+	if err := db.Where("id IN ?", ids).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// GetAttachmentsByMessageIDs returns the files sent with the messages, in the order they were uploaded
+func GetAttachmentsByMessageIDs(messageIDs []uint) ([]*entity.Attachment, error) {
+	var attachments []*entity.Attachment
+	if err := db.Where("message_id IN ?", messageIDs).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func GetAttachmentsByUserID(userID uint) ([]*entity.Attachment, error) {
+	var attachments []*entity.Attachment
+	if err := db.Where("user_id = ?", userID).Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// AttachToMessage links the files to the user message they were sent with
+func AttachToMessage(ids []uint, messageID uint) error {
+	return db.Model(&entity.Attachment{}).Where("id IN ?", ids).Update("message_id", messageID).Error
+}
+
+// GetUnsentAttachmentsBefore returns the files uploaded before the time that were never sent with a message
+func GetUnsentAttachmentsBefore(before time.Time) ([]*entity.Attachment, error) {
+	var attachments []*entity.Attachment
+	if err := db.Where("message_id = 0 AND create_time < ?", before).Order("id").Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func DeleteAttachment(attachmentID uint) error {
+	return db.Delete(&entity.Attachment{}, attachmentID).Error
+}
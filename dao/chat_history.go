@@ -1,15 +1,16 @@
 package dao
 
 import (
-	"easy-chat/agents/memory"
 	"easy-chat/entity"
 	"easy-chat/request"
+	"gorm.io/gorm"
+	"time"
 )
 
 func GetChatHistoryBySessionID(sessionID string) ([]*entity.ChatHistory, error) {
 	var chatHistories []*entity.ChatHistory
 
-	result := db.Where("session_id = ?", sessionID).Find(&chatHistories)
+	result := db.Where("session_id = ?", sessionID).Order("create_time, id").Find(&chatHistories)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -17,23 +18,68 @@ func GetChatHistoryBySessionID(sessionID string) ([]*entity.ChatHistory, error)
 	return chatHistories, nil
 }
 
-func SaveChatHistory(chatRequest *request.ChatRequest, messages []memory.Message) error {
+// GetChatHistoryTree returns only the ID and parent of every message of the session, oldest first,
+// which is all it takes to walk its branches
+func GetChatHistoryTree(sessionID string) ([]*entity.ChatHistory, error) {
+	var chatHistories []*entity.ChatHistory
+
+	result := db.Select("id", "parent_id").Where("session_id = ?", sessionID).Order("create_time, id").Find(&chatHistories)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return chatHistories, nil
+}
+
+// SaveChatHistory appends the messages to the branch of the request's parent message, or of the
+// session's active message, and makes the last of them the session's active message.
+// The user, session and parent of the messages are filled in.
+func SaveChatHistory(chatRequest *request.ChatRequest, chatHistories []*entity.ChatHistory) ([]*entity.ChatHistory, error) {
 	user, err := GetUserByUsername(chatRequest.Username)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	for _, message := range messages {
-		chatHistory := &entity.ChatHistory{
-			UserID:      user.ID,
-			SessionID:   chatRequest.SessionID,
-			MessageType: message.Role,
-			Content:     message.Content,
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var parentID uint
+		if chatRequest.ParentID != nil {
+			parentID = *chatRequest.ParentID
+		} else {
+			var session entity.ChatSession
+			if err := tx.Where("session_id = ?", chatRequest.SessionID).First(&session).Error; err != nil {
+				return err
+			}
+			parentID = session.ActiveMessageID
 		}
-		result := db.Create(chatHistory)
-		if result.Error != nil {
-			return result.Error
+
+		for _, chatHistory := range chatHistories {
+			chatHistory.UserID = user.ID
+			chatHistory.SessionID = chatRequest.SessionID
+			chatHistory.ParentID = parentID
+			if err := tx.Create(chatHistory).Error; err != nil {
+				return err
+			}
+			parentID = chatHistory.ID
 		}
+
+		return tx.Model(&entity.ChatSession{}).
+			Where("session_id = ?", chatRequest.SessionID).
+			Updates(map[string]interface{}{
+				"last_active_time":  time.Now(),
+				"active_message_id": parentID,
+			}).Error
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	return chatHistories, nil
+}
+
+func GetChatHistoryByIDs(ids []uint) ([]*entity.ChatHistory, error) {
+	var chatHistories []*entity.ChatHistory
+	if err := db.Where("id IN ?", ids).Find(&chatHistories).Error; err != nil {
+		return nil, err
+	}
+	return chatHistories, nil
 }
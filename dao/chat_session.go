@@ -2,43 +2,200 @@ package dao
 
 import (
 	"easy-chat/entity"
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
 )
 
-func CreateChatSession(username string) (string, error) {
-	sessionID := uuid.New().String()
+var ErrInvalidSessionStatus = errors.New("invalid session status")
+
+const (
+	SessionStatusActive   = "active"
+	SessionStatusArchived = "archived"
+	SessionStatusTrashed  = "trashed"
+)
+
+// CreateChatSession saves the session with its settings in a single insert, so it is never left half configured
+func CreateChatSession(username string, session *entity.ChatSession) (string, error) {
 	user, err := GetUserByUsername(username)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
-	session := &entity.ChatSession{
-		SessionID: sessionID,
-		UserID:    user.ID,
-	}
+	session.SessionID = uuid.New().String()
+	session.UserID = user.ID
 	result := db.Create(session)
 	if result.Error != nil {
 		return "", result.Error
 	}
 
-	return sessionID, nil
+	return session.SessionID, nil
 }
 
-func DeleteChatSession(sessionID string) error {
+// GetChatSessionByID also returns sessions that are in the trash
+func GetChatSessionByID(sessionID string) (*entity.ChatSession, error) {
+	var session entity.ChatSession
+	if err := db.Unscoped().Where("session_id = ?", sessionID).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func RenameChatSession(sessionID, sessionName string) error {
+	return db.Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).Update("session_name", sessionName).Error
+}
+
+func SetChatSessionMemoryStrategy(sessionID, strategy string) error {
+	return db.Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).Update("memory_strategy", strategy).Error
+}
+
+// chatSettingsFields are updated together, so clearing a setting back to its zero value is saved as well
+var chatSettingsFields = []string{"Model", "Mode", "Temperature", "TopP", "MaxTokens", "Tools"}
+
+func UpdateChatSessionSettings(sessionID string, settings entity.ChatSettings) error {
+	return db.Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).
+		Select(chatSettingsFields).
+		Updates(&entity.ChatSession{ChatSettings: settings}).Error
+}
+
+// SetChatSessionActiveMessage switches the session to the branch that ends with the message
+func SetChatSessionActiveMessage(sessionID string, messageID uint) error {
+	return db.Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).Update("active_message_id", messageID).Error
+}
+
+// SetChatSessionAssistant binds the session to the assistant, 0 unbinds it
+func SetChatSessionAssistant(sessionID string, assistantID uint) error {
+	return db.Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).Update("assistant_id", assistantID).Error
+}
+
+// SaveChatSessionSummary stores the rolling summary of the session's messages up to untilID
+func SaveChatSessionSummary(sessionID, summary string, untilID uint) error {
+	return db.Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).Updates(map[string]interface{}{
+		"memory_summary":          summary,
+		"memory_summary_until_id": untilID,
+	}).Error
+}
+
+func ArchiveChatSession(sessionID string, archived bool) error {
+	var archiveTime *time.Time
+	if archived {
+		now := time.Now()
+		archiveTime = &now
+	}
+	return db.Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).Update("archive_time", archiveTime).Error
+}
+
+// TrashChatSession soft-deletes the session, its messages are kept until the trash is purged
+func TrashChatSession(sessionID string) error {
 	return db.Where("session_id = ?", sessionID).Delete(&entity.ChatSession{}).Error
 }
 
-func GetChatSessionByUsername(username string) ([]*entity.ChatSession, error) {
-	user, err := GetUserByUsername(username)
+func RestoreChatSession(sessionID string) error {
+	return db.Unscoped().Model(&entity.ChatSession{}).Where("session_id = ?", sessionID).Update("delete_time", nil).Error
+}
+
+// DeleteChatSession permanently removes the session and all of its messages in one transaction
+func DeleteChatSession(sessionID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteChatSessions(tx, []string{sessionID})
+	})
+}
+
+// PurgeTrashedChatSessions permanently removes sessions that were moved to the trash before the given time
+// and returns their IDs
+func PurgeTrashedChatSessions(before time.Time) ([]string, error) {
+	var sessionIDs []string
+	err := db.Unscoped().Model(&entity.ChatSession{}).
+		Where("delete_time IS NOT NULL AND delete_time < ?", before).
+		Pluck("session_id", &sessionIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sessionIDs) == 0 {
+		return nil, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return deleteChatSessions(tx, sessionIDs)
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return sessionIDs, nil
+}
+
+// GetChatSessionByUsername returns one page of the user's sessions, most recently active first,
+// and whether more sessions exist
+func GetChatSessionByUsername(username string, status string, page SessionPage) ([]*entity.ChatSession, bool, error) {
+	user, err := GetUserByUsername(username)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := db.Where("user_id = ?", user.ID)
+	switch status {
+	case SessionStatusActive:
+		query = query.Where("archive_time IS NULL")
+	case SessionStatusArchived:
+		query = query.Where("archive_time IS NOT NULL")
+	case SessionStatusTrashed:
+		query = query.Unscoped().Where("delete_time IS NOT NULL")
+	default:
+		return nil, false, fmt.Errorf("%w: %s", ErrInvalidSessionStatus, status)
+	}
+
+	if page.BeforeID != "" {
+		// the cursor must be one of the user's sessions, trashed ones included for the trash listing
+		var cursor entity.ChatSession
+		err := db.Unscoped().Where("session_id = ? AND user_id = ?", page.BeforeID, user.ID).First(&cursor).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, fmt.Errorf("%w: %s", ErrCursorNotFound, page.BeforeID)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		query = query.Where("last_active_time < ? OR (last_active_time = ? AND session_id < ?)",
+			cursor.LastActiveTime, cursor.LastActiveTime, cursor.SessionID)
+	}
+
+	limit := normalizeLimit(page.Limit)
+
 	var sessions []*entity.ChatSession
-	result := db.Where("user_id = ?", user.ID).Find(&sessions)
+	result := query.Order("last_active_time DESC, session_id DESC").Limit(limit + 1).Find(&sessions)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, false, result.Error
 	}
 
-	return sessions, nil
+	hasMore := len(sessions) > limit
+	if hasMore {
+		sessions = sessions[:limit]
+	}
+
+	return sessions, hasMore, nil
+}
+
+func deleteChatSessions(tx *gorm.DB, sessionIDs []string) error {
+	if err := tx.Where("session_id IN ?", sessionIDs).Delete(&entity.ChatHistory{}).Error; err != nil {
+		return err
+	}
+	if err := deleteDocuments(tx, tx.Where("session_id IN ?", sessionIDs)); err != nil {
+		return err
+	}
+	if err := tx.Where("session_id IN ?", sessionIDs).Delete(&entity.SharedLink{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("session_id IN ?", sessionIDs).Delete(&entity.MessageFeedback{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("session_id IN ?", sessionIDs).Delete(&entity.Attachment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("session_id IN ?", sessionIDs).Delete(&entity.ModelComparison{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("session_id IN ?", sessionIDs).Delete(&entity.ChatSession{}).Error
 }
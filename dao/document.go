@@ -0,0 +1,85 @@
+package dao
+
+import (
+	"easy-chat/entity"
+
+	"gorm.io/gorm"
+)
+
+// CreateDocument saves the document together with its chunks and fills in their IDs
+func CreateDocument(document *entity.Document, chunks []*entity.DocumentChunk) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		document.ChunkCount = len(chunks)
+		if err := tx.Create(document).Error; err != nil {
+			return err
+		}
+
+		if len(chunks) == 0 {
+			return nil
+		}
+		for _, chunk := range chunks {
+			chunk.DocumentID = document.ID
+		}
+		return tx.Create(&chunks).Error
+	})
+}
+
+func GetDocumentByID(documentID uint) (*entity.Document, error) {
+	var document entity.Document
+	if err := db.First(&document, documentID).Error; err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// GetDocumentsByUserID returns the user's shared documents, plus the ones of the session if sessionID is set
+func GetDocumentsByUserID(userID uint, sessionID string) ([]*entity.Document, error) {
+	var documents []*entity.Document
+
+	query := db.Where("user_id = ?", userID)
+	if sessionID == "" {
+		query = query.Where("session_id = ?", "")
+	} else {
+		query = query.Where("session_id IN ?", []string{"", sessionID})
+	}
+
+	if err := query.Order("create_time DESC, id DESC").Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+func GetDocumentChunksByIDs(ids []uint) ([]*entity.DocumentChunk, error) {
+	var chunks []*entity.DocumentChunk
+	if len(ids) == 0 {
+		return chunks, nil
+	}
+
+	if err := db.Where("id IN ?", ids).Find(&chunks).Error; err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func DeleteDocument(documentID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return deleteDocuments(tx, tx.Where("id = ?", documentID))
+	})
+}
+
+// deleteDocuments removes the documents matched by the scoped query and their chunks.
+// The IDs are read first because MySQL can not delete from a table it selects from.
+func deleteDocuments(tx *gorm.DB, scope *gorm.DB) error {
+	var documentIDs []uint
+	if err := scope.Model(&entity.Document{}).Pluck("id", &documentIDs).Error; err != nil {
+		return err
+	}
+	if len(documentIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Where("document_id IN ?", documentIDs).Delete(&entity.DocumentChunk{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("id IN ?", documentIDs).Delete(&entity.Document{}).Error
+}
@@ -2,10 +2,30 @@ package dao
 
 import (
 	"easy-chat/config"
+	"errors"
+	"fmt"
+	"time"
+
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+const (
+	defaultHost       = "127.0.0.1"
+	defaultName       = "chat"
+	defaultSQLitePath = "chat.db"
+)
+
 var db *gorm.DB
 
 func Init() error {
@@ -15,20 +35,83 @@ func Init() error {
 	return nil
 }
 
+// DB exposes the connection to stores that manage their own tables, such as the vector store
+func DB() *gorm.DB {
+	return db
+}
+
 func initDB() error {
-	dsn := buildDSN()
-	var err error
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	dialector, err := buildDialector()
+	if err != nil {
+		return err
+	}
+
+	db, err = gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return err
+	}
+
+	return configurePool()
+}
+
+func buildDialector() (gorm.Dialector, error) {
+	switch driver := config.Get().DataBase.Driver; driver {
+	case "", DriverMySQL:
+		return mysql.Open(buildMySQLDSN()), nil
+	case DriverPostgres:
+		return postgres.Open(buildPostgresDSN()), nil
+	case DriverSQLite:
+		return sqlite.Open(buildSQLiteDSN()), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDriver, driver)
+	}
+}
+
+func configurePool() error {
+	sqlDB, err := db.DB()
 	if err != nil {
 		return err
 	}
+
+	pool := config.Get().DataBase.Pool
+	if pool.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(time.Duration(pool.ConnMaxLifetime) * time.Second)
+	}
 	return nil
 }
 
-func buildDSN() string {
-	cfg := config.Get()
-	port := cfg.DataBase.Mysql.Port
-	username := cfg.DataBase.Mysql.Username
-	password := cfg.DataBase.Mysql.Password
-	return username + ":" + password + "@tcp(127.0.0.1:" + port + ")/chat?charset=utf8mb4&parseTime=True&loc=Local"
+func buildMySQLDSN() string {
+	cfg := config.Get().DataBase.Mysql
+	host := valueOrDefault(cfg.Host, defaultHost)
+	name := valueOrDefault(cfg.Name, defaultName)
+	return cfg.Username + ":" + cfg.Password + "@tcp(" + host + ":" + cfg.Port + ")/" + name + "?charset=utf8mb4&parseTime=True&loc=Local"
+}
+
+func buildPostgresDSN() string {
+	cfg := config.Get().DataBase.Postgres
+	host := valueOrDefault(cfg.Host, defaultHost)
+	port := valueOrDefault(cfg.Port, "5432")
+	name := valueOrDefault(cfg.Name, defaultName)
+	sslMode := valueOrDefault(cfg.SSLMode, "disable")
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		host, port, cfg.Username, cfg.Password, name, sslMode)
+}
+
+// buildSQLiteDSN turns on foreign keys and a busy timeout, which sqlite leaves off by default
+func buildSQLiteDSN() string {
+	path := valueOrDefault(config.Get().DataBase.SQLite.Path, defaultSQLitePath)
+	return "file:" + path + "?_foreign_keys=on&_busy_timeout=5000"
+}
+
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
 }
@@ -0,0 +1,97 @@
+package inmemory
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type assistantRepository struct {
+	store *Store
+}
+
+func (s *Store) deleteAssistants(userID uint) {
+	for assistantID, assistant := range s.assistants {
+		if assistant.UserID == userID {
+			delete(s.assistants, assistantID)
+		}
+	}
+}
+
+func (r *assistantRepository) Create(assistant *entity.Assistant) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	assistant.ID = r.store.nextAssistantID
+	assistant.CreateTime = now
+	assistant.UpdateTime = now
+	r.store.nextAssistantID++
+
+	copied := *assistant
+	r.store.assistants[assistant.ID] = &copied
+	return nil
+}
+
+func (r *assistantRepository) GetByID(assistantID uint) (*entity.Assistant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	assistant, exists := r.store.assistants[assistantID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	copied := *assistant
+	return &copied, nil
+}
+
+func (r *assistantRepository) GetByUserID(userID uint) ([]*entity.Assistant, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var assistants []*entity.Assistant
+	for _, assistant := range r.store.assistants {
+		if assistant.UserID == userID {
+			copied := *assistant
+			assistants = append(assistants, &copied)
+		}
+	}
+
+	slices.SortFunc(assistants, func(a, b *entity.Assistant) int {
+		return int(a.ID) - int(b.ID)
+	})
+	return assistants, nil
+}
+
+func (r *assistantRepository) Update(assistant *entity.Assistant) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, exists := r.store.assistants[assistant.ID]
+	if !exists {
+		return nil
+	}
+
+	copied := *assistant
+	copied.UserID = stored.UserID
+	copied.CreateTime = stored.CreateTime
+	copied.UpdateTime = time.Now()
+	r.store.assistants[assistant.ID] = &copied
+	return nil
+}
+
+func (r *assistantRepository) Delete(assistantID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, session := range r.store.sessions {
+		if session.AssistantID == assistantID {
+			session.AssistantID = 0
+		}
+	}
+	delete(r.store.assistants, assistantID)
+	return nil
+}
@@ -0,0 +1,104 @@
+package inmemory
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"time"
+)
+
+type attachmentRepository struct {
+	store *Store
+}
+
+func (s *Store) deleteAttachments(match func(attachment *entity.Attachment) bool) {
+	for id, attachment := range s.attachments {
+		if match(attachment) {
+			delete(s.attachments, id)
+		}
+	}
+}
+
+// listAttachments returns copies of the matching attachments, in the order they were uploaded
+func (s *Store) listAttachments(match func(attachment *entity.Attachment) bool) []*entity.Attachment {
+	var attachments []*entity.Attachment
+	for _, attachment := range s.attachments {
+		if match(attachment) {
+			copied := *attachment
+			attachments = append(attachments, &copied)
+		}
+	}
+
+	slices.SortFunc(attachments, func(a, b *entity.Attachment) int {
+		return int(a.ID) - int(b.ID)
+	})
+	return attachments
+}
+
+func (r *attachmentRepository) Create(attachment *entity.Attachment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	attachment.ID = r.store.nextAttachmentID
+	attachment.CreateTime = time.Now()
+	r.store.nextAttachmentID++
+
+	copied := *attachment
+	r.store.attachments[attachment.ID] = &copied
+	return nil
+}
+
+func (r *attachmentRepository) GetByIDs(ids []uint) ([]*entity.Attachment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.listAttachments(func(attachment *entity.Attachment) bool {
+		return slices.Contains(ids, attachment.ID)
+	}), nil
+}
+
+func (r *attachmentRepository) GetByMessageIDs(messageIDs []uint) ([]*entity.Attachment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.listAttachments(func(attachment *entity.Attachment) bool {
+		return slices.Contains(messageIDs, attachment.MessageID)
+	}), nil
+}
+
+func (r *attachmentRepository) GetByUserID(userID uint) ([]*entity.Attachment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.listAttachments(func(attachment *entity.Attachment) bool {
+		return attachment.UserID == userID
+	}), nil
+}
+
+func (r *attachmentRepository) AttachToMessage(ids []uint, messageID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, id := range ids {
+		if attachment, exists := r.store.attachments[id]; exists {
+			attachment.MessageID = messageID
+		}
+	}
+	return nil
+}
+
+func (r *attachmentRepository) GetUnsentBefore(before time.Time) ([]*entity.Attachment, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	return r.store.listAttachments(func(attachment *entity.Attachment) bool {
+		return attachment.MessageID == 0 && attachment.CreateTime.Before(before)
+	}), nil
+}
+
+func (r *attachmentRepository) Delete(attachmentID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.attachments, attachmentID)
+	return nil
+}
@@ -0,0 +1,98 @@
+package inmemory
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type documentRepository struct {
+	store *Store
+}
+
+func (r *documentRepository) Create(document *entity.Document, chunks []*entity.DocumentChunk) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	document.ID = r.store.nextDocumentID
+	document.CreateTime = now
+	document.UpdateTime = now
+	document.ChunkCount = len(chunks)
+	r.store.nextDocumentID++
+
+	copied := *document
+	r.store.documents[document.ID] = &copied
+
+	for _, chunk := range chunks {
+		chunk.ID = r.store.nextChunkID
+		chunk.CreateTime = now
+		chunk.DocumentID = document.ID
+		r.store.nextChunkID++
+
+		copiedChunk := *chunk
+		r.store.documentChunks[chunk.ID] = &copiedChunk
+	}
+	return nil
+}
+
+func (r *documentRepository) GetByID(documentID uint) (*entity.Document, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	document, exists := r.store.documents[documentID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	copied := *document
+	return &copied, nil
+}
+
+func (r *documentRepository) GetByUserID(userID uint, sessionID string) ([]*entity.Document, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var documents []*entity.Document
+	for _, document := range r.store.documents {
+		if document.UserID != userID || (document.SessionID != "" && document.SessionID != sessionID) {
+			continue
+		}
+		copied := *document
+		documents = append(documents, &copied)
+	}
+
+	slices.SortFunc(documents, func(a, b *entity.Document) int {
+		if !a.CreateTime.Equal(b.CreateTime) {
+			return b.CreateTime.Compare(a.CreateTime)
+		}
+		return int(b.ID) - int(a.ID)
+	})
+	return documents, nil
+}
+
+func (r *documentRepository) GetChunksByIDs(ids []uint) ([]*entity.DocumentChunk, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	chunks := make([]*entity.DocumentChunk, 0, len(ids))
+	for _, id := range ids {
+		if chunk, exists := r.store.documentChunks[id]; exists {
+			copied := *chunk
+			chunks = append(chunks, &copied)
+		}
+	}
+	return chunks, nil
+}
+
+func (r *documentRepository) Delete(documentID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.deleteDocuments(func(document *entity.Document) bool {
+		return document.ID == documentID
+	})
+	return nil
+}
@@ -0,0 +1,85 @@
+package inmemory
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type feedbackRepository struct {
+	store *Store
+}
+
+func (s *Store) deleteFeedback(match func(feedback *entity.MessageFeedback) bool) {
+	for messageID, feedback := range s.feedback {
+		if match(feedback) {
+			delete(s.feedback, messageID)
+		}
+	}
+}
+
+func (r *feedbackRepository) Save(feedback *entity.MessageFeedback) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	if stored, exists := r.store.feedback[feedback.MessageID]; exists {
+		stored.Rating = feedback.Rating
+		stored.Category = feedback.Category
+		stored.Comment = feedback.Comment
+		stored.UpdateTime = now
+		return nil
+	}
+
+	feedback.ID = r.store.nextFeedbackID
+	feedback.CreateTime = now
+	feedback.UpdateTime = now
+	r.store.nextFeedbackID++
+
+	copied := *feedback
+	r.store.feedback[feedback.MessageID] = &copied
+	return nil
+}
+
+func (r *feedbackRepository) GetByMessageID(messageID uint) (*entity.MessageFeedback, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	feedback, exists := r.store.feedback[messageID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	copied := *feedback
+	return &copied, nil
+}
+
+func (r *feedbackRepository) Delete(messageID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.feedback, messageID)
+	return nil
+}
+
+func (r *feedbackRepository) GetBetween(from, to time.Time) ([]*entity.MessageFeedback, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var feedback []*entity.MessageFeedback
+	for _, stored := range r.store.feedback {
+		if stored.CreateTime.Before(from) || !stored.CreateTime.Before(to) {
+			continue
+		}
+		copied := *stored
+		copied.Comment = ""
+		feedback = append(feedback, &copied)
+	}
+
+	slices.SortFunc(feedback, func(a, b *entity.MessageFeedback) int {
+		return a.CreateTime.Compare(b.CreateTime)
+	})
+	return feedback, nil
+}
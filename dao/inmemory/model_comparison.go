@@ -0,0 +1,81 @@
+package inmemory
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type comparisonRepository struct {
+	store *Store
+}
+
+func (s *Store) deleteComparisons(match func(comparison *entity.ModelComparison) bool) {
+	for id, comparison := range s.comparisons {
+		if match(comparison) {
+			delete(s.comparisons, id)
+		}
+	}
+}
+
+func (r *comparisonRepository) Create(comparison *entity.ModelComparison) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	comparison.ID = r.store.nextComparisonID
+	comparison.CreateTime = now
+	comparison.UpdateTime = now
+	r.store.nextComparisonID++
+
+	copied := *comparison
+	r.store.comparisons[comparison.ID] = &copied
+	return nil
+}
+
+func (r *comparisonRepository) GetByMessageID(messageID uint) (*entity.ModelComparison, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, comparison := range r.store.comparisons {
+		if comparison.MessageID == messageID {
+			copied := *comparison
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *comparisonRepository) SetPreference(comparisonID, messageID uint, model string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	comparison, exists := r.store.comparisons[comparisonID]
+	if !exists {
+		return gorm.ErrRecordNotFound
+	}
+	comparison.PreferredMessageID = messageID
+	comparison.PreferredModel = model
+	comparison.UpdateTime = time.Now()
+	return nil
+}
+
+func (r *comparisonRepository) GetPreferred() ([]*entity.ModelComparison, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var comparisons []*entity.ModelComparison
+	for _, comparison := range r.store.comparisons {
+		if comparison.PreferredMessageID != 0 {
+			copied := *comparison
+			comparisons = append(comparisons, &copied)
+		}
+	}
+
+	slices.SortFunc(comparisons, func(a, b *entity.ModelComparison) int {
+		return int(a.ID) - int(b.ID)
+	})
+	return comparisons, nil
+}
@@ -0,0 +1,93 @@
+package inmemory
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type sharedLinkRepository struct {
+	store *Store
+}
+
+func (s *Store) deleteSharedLinks(match func(sharedLink *entity.SharedLink) bool) {
+	for sharedLinkID, sharedLink := range s.sharedLinks {
+		if match(sharedLink) {
+			delete(s.sharedLinks, sharedLinkID)
+		}
+	}
+}
+
+func (r *sharedLinkRepository) Create(sharedLink *entity.SharedLink) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, stored := range r.store.sharedLinks {
+		if stored.Token == sharedLink.Token {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	sharedLink.ID = r.store.nextSharedLinkID
+	sharedLink.CreateTime = time.Now()
+	r.store.nextSharedLinkID++
+
+	copied := *sharedLink
+	r.store.sharedLinks[sharedLink.ID] = &copied
+	return nil
+}
+
+func (r *sharedLinkRepository) GetByID(sharedLinkID uint) (*entity.SharedLink, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	sharedLink, exists := r.store.sharedLinks[sharedLinkID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	copied := *sharedLink
+	return &copied, nil
+}
+
+func (r *sharedLinkRepository) GetByToken(token string) (*entity.SharedLink, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, sharedLink := range r.store.sharedLinks {
+		if sharedLink.Token == token {
+			copied := *sharedLink
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *sharedLinkRepository) GetByUserID(userID uint) ([]*entity.SharedLink, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var sharedLinks []*entity.SharedLink
+	for _, sharedLink := range r.store.sharedLinks {
+		if sharedLink.UserID == userID {
+			copied := *sharedLink
+			copied.Snapshot = ""
+			sharedLinks = append(sharedLinks, &copied)
+		}
+	}
+
+	slices.SortFunc(sharedLinks, func(a, b *entity.SharedLink) int {
+		return int(b.ID) - int(a.ID)
+	})
+	return sharedLinks, nil
+}
+
+func (r *sharedLinkRepository) Delete(sharedLinkID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.sharedLinks, sharedLinkID)
+	return nil
+}
@@ -0,0 +1,616 @@
+// Package inmemory implements the dao repositories on top of plain maps, so
+// that service and agent code can be exercised without a database.
+package inmemory
+
+import (
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	_ dao.UserRepository       = (*userRepository)(nil)
+	_ dao.SessionRepository    = (*sessionRepository)(nil)
+	_ dao.HistoryRepository    = (*historyRepository)(nil)
+	_ dao.DocumentRepository   = (*documentRepository)(nil)
+	_ dao.UserMemoryRepository = (*userMemoryRepository)(nil)
+	_ dao.AssistantRepository  = (*assistantRepository)(nil)
+	_ dao.SharedLinkRepository = (*sharedLinkRepository)(nil)
+	_ dao.FeedbackRepository   = (*feedbackRepository)(nil)
+	_ dao.AttachmentRepository = (*attachmentRepository)(nil)
+	_ dao.ComparisonRepository = (*comparisonRepository)(nil)
+)
+
+type Store struct {
+	mu               sync.Mutex
+	users            map[uint]*entity.User
+	sessions         map[string]*entity.ChatSession
+	histories        []*entity.ChatHistory
+	documents        map[uint]*entity.Document
+	documentChunks   map[uint]*entity.DocumentChunk
+	userMemories     map[uint]*entity.UserMemory
+	assistants       map[uint]*entity.Assistant
+	sharedLinks      map[uint]*entity.SharedLink
+	feedback         map[uint]*entity.MessageFeedback
+	attachments      map[uint]*entity.Attachment
+	comparisons      map[uint]*entity.ModelComparison
+	nextUserID       uint
+	nextHistoryID    uint
+	nextDocumentID   uint
+	nextChunkID      uint
+	nextMemoryID     uint
+	nextAssistantID  uint
+	nextSharedLinkID uint
+	nextFeedbackID   uint
+	nextAttachmentID uint
+	nextComparisonID uint
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:            make(map[uint]*entity.User),
+		sessions:         make(map[string]*entity.ChatSession),
+		documents:        make(map[uint]*entity.Document),
+		documentChunks:   make(map[uint]*entity.DocumentChunk),
+		userMemories:     make(map[uint]*entity.UserMemory),
+		assistants:       make(map[uint]*entity.Assistant),
+		sharedLinks:      make(map[uint]*entity.SharedLink),
+		feedback:         make(map[uint]*entity.MessageFeedback),
+		attachments:      make(map[uint]*entity.Attachment),
+		comparisons:      make(map[uint]*entity.ModelComparison),
+		nextUserID:       1,
+		nextHistoryID:    1,
+		nextDocumentID:   1,
+		nextChunkID:      1,
+		nextMemoryID:     1,
+		nextAssistantID:  1,
+		nextSharedLinkID: 1,
+		nextFeedbackID:   1,
+		nextAttachmentID: 1,
+		nextComparisonID: 1,
+	}
+}
+
+// Repositories returns repositories that share this store
+func (s *Store) Repositories() *dao.Repositories {
+	return &dao.Repositories{
+		Users:        &userRepository{store: s},
+		Sessions:     &sessionRepository{store: s},
+		Histories:    &historyRepository{store: s},
+		Documents:    &documentRepository{store: s},
+		UserMemories: &userMemoryRepository{store: s},
+		Assistants:   &assistantRepository{store: s},
+		SharedLinks:  &sharedLinkRepository{store: s},
+		Feedback:     &feedbackRepository{store: s},
+		Attachments:  &attachmentRepository{store: s},
+		Comparisons:  &comparisonRepository{store: s},
+	}
+}
+
+func (s *Store) findUserByUsername(username string) (*entity.User, error) {
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *Store) deleteSession(sessionID string) {
+	delete(s.sessions, sessionID)
+
+	histories := s.histories[:0]
+	for _, history := range s.histories {
+		if history.SessionID != sessionID {
+			histories = append(histories, history)
+		}
+	}
+	s.histories = histories
+
+	s.deleteDocuments(func(document *entity.Document) bool {
+		return document.SessionID == sessionID
+	})
+	s.deleteSharedLinks(func(sharedLink *entity.SharedLink) bool {
+		return sharedLink.SessionID == sessionID
+	})
+	s.deleteFeedback(func(feedback *entity.MessageFeedback) bool {
+		return feedback.SessionID == sessionID
+	})
+	s.deleteAttachments(func(attachment *entity.Attachment) bool {
+		return attachment.SessionID == sessionID
+	})
+	s.deleteComparisons(func(comparison *entity.ModelComparison) bool {
+		return comparison.SessionID == sessionID
+	})
+}
+
+func (s *Store) deleteDocuments(match func(document *entity.Document) bool) {
+	for documentID, document := range s.documents {
+		if !match(document) {
+			continue
+		}
+		delete(s.documents, documentID)
+		for chunkID, chunk := range s.documentChunks {
+			if chunk.DocumentID == documentID {
+				delete(s.documentChunks, chunkID)
+			}
+		}
+	}
+}
+
+type userRepository struct {
+	store *Store
+}
+
+func (r *userRepository) Create(request *request.UserRegisterRequest) (*entity.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.store.users {
+		if user.Username == request.Username {
+			return nil, fmt.Errorf("%w: %s", dao.ErrUsernameAlreadyExists, request.Username)
+		}
+		if user.Email == request.Email {
+			return nil, fmt.Errorf("%w: %s", dao.ErrEmailAlreadyExists, request.Email)
+		}
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(request.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &entity.User{
+		ID:            r.store.nextUserID,
+		CreateTime:    now,
+		UpDateTime:    now,
+		Username:      request.Username,
+		Email:         request.Email,
+		Password:      string(passwordHash),
+		MemoryEnabled: true,
+	}
+	r.store.users[user.ID] = user
+	r.store.nextUserID++
+
+	copied := *user
+	return &copied, nil
+}
+
+func (r *userRepository) GetByUsername(username string) (*entity.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := r.store.findUserByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *user
+	return &copied, nil
+}
+
+func (r *userRepository) Update(user *entity.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	copied := *user
+	copied.UpDateTime = time.Now()
+	r.store.users[user.ID] = &copied
+	return nil
+}
+
+func (r *userRepository) Delete(userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for sessionID, session := range r.store.sessions {
+		if session.UserID == userID {
+			delete(r.store.sessions, sessionID)
+		}
+	}
+
+	histories := r.store.histories[:0]
+	for _, history := range r.store.histories {
+		if history.UserID != userID {
+			histories = append(histories, history)
+		}
+	}
+	r.store.histories = histories
+
+	r.store.deleteDocuments(func(document *entity.Document) bool {
+		return document.UserID == userID
+	})
+	r.store.deleteUserMemories(userID)
+	r.store.deleteAssistants(userID)
+	r.store.deleteSharedLinks(func(sharedLink *entity.SharedLink) bool {
+		return sharedLink.UserID == userID
+	})
+	r.store.deleteFeedback(func(feedback *entity.MessageFeedback) bool {
+		return feedback.UserID == userID
+	})
+	r.store.deleteAttachments(func(attachment *entity.Attachment) bool {
+		return attachment.UserID == userID
+	})
+	r.store.deleteComparisons(func(comparison *entity.ModelComparison) bool {
+		return comparison.UserID == userID
+	})
+
+	delete(r.store.users, userID)
+	return nil
+}
+
+func (r *userRepository) CheckEmailExists(email string, excludeUserID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, user := range r.store.users {
+		if user.Email == email && user.ID != excludeUserID {
+			return fmt.Errorf("%w: %s", dao.ErrEmailAlreadyExists, email)
+		}
+	}
+	return nil
+}
+
+type sessionRepository struct {
+	store *Store
+}
+
+func (r *sessionRepository) Create(username string, session *entity.ChatSession) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := r.store.findUserByUsername(username)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session.SessionID = uuid.New().String()
+	session.CreateTime = now
+	session.UpdateTime = now
+	session.UserID = user.ID
+	session.LastActiveTime = now
+
+	copied := *session
+	r.store.sessions[session.SessionID] = &copied
+
+	return session.SessionID, nil
+}
+
+func (r *sessionRepository) GetByID(sessionID string) (*entity.ChatSession, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, exists := r.store.sessions[sessionID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+func (r *sessionRepository) GetByUsername(username string, status string, page dao.SessionPage) ([]*entity.ChatSession, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := r.store.findUserByUsername(username)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var sessions []*entity.ChatSession
+	for _, session := range r.store.sessions {
+		if session.UserID != user.ID {
+			continue
+		}
+
+		trashed := session.DeleteTime.Valid
+		var matched bool
+		switch status {
+		case dao.SessionStatusActive:
+			matched = !trashed && session.ArchiveTime == nil
+		case dao.SessionStatusArchived:
+			matched = !trashed && session.ArchiveTime != nil
+		case dao.SessionStatusTrashed:
+			matched = trashed
+		default:
+			return nil, false, fmt.Errorf("%w: %s", dao.ErrInvalidSessionStatus, status)
+		}
+
+		if matched {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+
+	slices.SortFunc(sessions, compareByLastActivity)
+
+	if page.BeforeID != "" {
+		cursor, exists := r.store.sessions[page.BeforeID]
+		if !exists || cursor.UserID != user.ID {
+			return nil, false, fmt.Errorf("%w: %s", dao.ErrCursorNotFound, page.BeforeID)
+		}
+		index := slices.IndexFunc(sessions, func(session *entity.ChatSession) bool {
+			return compareByLastActivity(session, cursor) > 0
+		})
+		if index < 0 {
+			index = len(sessions)
+		}
+		sessions = sessions[index:]
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = dao.DefaultPageLimit
+	}
+	limit = min(limit, dao.MaxPageLimit)
+
+	hasMore := len(sessions) > limit
+	if hasMore {
+		sessions = sessions[:limit]
+	}
+	return sessions, hasMore, nil
+}
+
+// compareByLastActivity orders the most recently active session first
+func compareByLastActivity(a, b *entity.ChatSession) int {
+	if c := b.LastActiveTime.Compare(a.LastActiveTime); c != 0 {
+		return c
+	}
+	return strings.Compare(b.SessionID, a.SessionID)
+}
+
+func (r *sessionRepository) Rename(sessionID, sessionName string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists && !session.DeleteTime.Valid {
+		session.SessionName = sessionName
+	}
+	return nil
+}
+
+func (r *sessionRepository) SetMemoryStrategy(sessionID, strategy string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists && !session.DeleteTime.Valid {
+		session.MemoryStrategy = strategy
+	}
+	return nil
+}
+
+func (r *sessionRepository) SetAssistant(sessionID string, assistantID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists && !session.DeleteTime.Valid {
+		session.AssistantID = assistantID
+	}
+	return nil
+}
+
+func (r *sessionRepository) SetActiveMessage(sessionID string, messageID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists && !session.DeleteTime.Valid {
+		session.ActiveMessageID = messageID
+	}
+	return nil
+}
+
+func (r *sessionRepository) UpdateSettings(sessionID string, settings entity.ChatSettings) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists && !session.DeleteTime.Valid {
+		session.ChatSettings = settings
+	}
+	return nil
+}
+
+func (r *sessionRepository) SaveSummary(sessionID, summary string, untilID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists && !session.DeleteTime.Valid {
+		session.MemorySummary = summary
+		session.MemorySummaryUntilID = untilID
+	}
+	return nil
+}
+
+func (r *sessionRepository) Archive(sessionID string, archived bool) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	session, exists := r.store.sessions[sessionID]
+	if !exists || session.DeleteTime.Valid {
+		return nil
+	}
+
+	session.ArchiveTime = nil
+	if archived {
+		now := time.Now()
+		session.ArchiveTime = &now
+	}
+	return nil
+}
+
+func (r *sessionRepository) Trash(sessionID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists && !session.DeleteTime.Valid {
+		session.DeleteTime = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+func (r *sessionRepository) Restore(sessionID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, exists := r.store.sessions[sessionID]; exists {
+		session.DeleteTime = gorm.DeletedAt{}
+	}
+	return nil
+}
+
+func (r *sessionRepository) Delete(sessionID string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.deleteSession(sessionID)
+	return nil
+}
+
+func (r *sessionRepository) PurgeTrashed(before time.Time) ([]string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var purged []string
+	for sessionID, session := range r.store.sessions {
+		if session.DeleteTime.Valid && session.DeleteTime.Time.Before(before) {
+			r.store.deleteSession(sessionID)
+			purged = append(purged, sessionID)
+		}
+	}
+	return purged, nil
+}
+
+type historyRepository struct {
+	store *Store
+}
+
+func (r *historyRepository) GetBySessionID(sessionID string) ([]*entity.ChatHistory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var histories []*entity.ChatHistory
+	for _, history := range r.store.histories {
+		if history.SessionID == sessionID {
+			copied := *history
+			histories = append(histories, &copied)
+		}
+	}
+	return histories, nil
+}
+
+func (r *historyRepository) GetTree(sessionID string) ([]*entity.ChatHistory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var histories []*entity.ChatHistory
+	for _, history := range r.store.histories {
+		if history.SessionID == sessionID {
+			histories = append(histories, &entity.ChatHistory{ID: history.ID, ParentID: history.ParentID})
+		}
+	}
+	return histories, nil
+}
+
+func (r *historyRepository) GetByIDs(ids []uint) ([]*entity.ChatHistory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var histories []*entity.ChatHistory
+	for _, history := range r.store.histories {
+		if slices.Contains(ids, history.ID) {
+			copied := *history
+			histories = append(histories, &copied)
+		}
+	}
+	return histories, nil
+}
+
+func (r *historyRepository) Save(chatRequest *request.ChatRequest, chatHistories []*entity.ChatHistory) ([]*entity.ChatHistory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, err := r.store.findUserByUsername(chatRequest.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	session, exists := r.store.sessions[chatRequest.SessionID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	parentID := session.ActiveMessageID
+	if chatRequest.ParentID != nil {
+		parentID = *chatRequest.ParentID
+	}
+
+	now := time.Now()
+	for _, chatHistory := range chatHistories {
+		chatHistory.ID = r.store.nextHistoryID
+		if chatHistory.CreateTime.IsZero() {
+			chatHistory.CreateTime = now
+		}
+		chatHistory.UpdateTime = now
+		chatHistory.UserID = user.ID
+		chatHistory.SessionID = chatRequest.SessionID
+		chatHistory.ParentID = parentID
+		r.store.nextHistoryID++
+		parentID = chatHistory.ID
+
+		copied := *chatHistory
+		r.store.histories = append(r.store.histories, &copied)
+	}
+
+	session.LastActiveTime = now
+	session.ActiveMessageID = parentID
+	return chatHistories, nil
+}
+
+func (r *historyRepository) Search(filter *dao.SearchFilter) ([]*dao.SearchResult, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var results []*dao.SearchResult
+	for i := len(r.store.histories) - 1; i >= 0; i-- {
+		history := r.store.histories[i]
+		session, exists := r.store.sessions[history.SessionID]
+		if !exists || session.DeleteTime.Valid || !matchesSearchFilter(history, filter) {
+			continue
+		}
+
+		results = append(results, &dao.SearchResult{ChatHistory: *history, SessionName: session.SessionName})
+		if filter.Limit > 0 && len(results) == filter.Limit {
+			break
+		}
+	}
+	return results, nil
+}
+
+func matchesSearchFilter(history *entity.ChatHistory, filter *dao.SearchFilter) bool {
+	if history.UserID != filter.UserID ||
+		(filter.SessionID != "" && history.SessionID != filter.SessionID) ||
+		(filter.Role != "" && history.MessageType != filter.Role) ||
+		(!filter.From.IsZero() && history.CreateTime.Before(filter.From)) ||
+		(!filter.To.IsZero() && !history.CreateTime.Before(filter.To)) {
+		return false
+	}
+
+	content := strings.ToLower(history.Content)
+	for _, term := range filter.Terms {
+		if !strings.Contains(content, strings.ToLower(term)) {
+			return false
+		}
+	}
+	return true
+}
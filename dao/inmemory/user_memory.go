@@ -0,0 +1,99 @@
+package inmemory
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type userMemoryRepository struct {
+	store *Store
+}
+
+func (s *Store) deleteUserMemories(userID uint) {
+	for memoryID, memory := range s.userMemories {
+		if memory.UserID == userID {
+			delete(s.userMemories, memoryID)
+		}
+	}
+}
+
+func (r *userMemoryRepository) Create(memories []*entity.UserMemory) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, memory := range memories {
+		memory.ID = r.store.nextMemoryID
+		memory.CreateTime = now
+		memory.UpdateTime = now
+		r.store.nextMemoryID++
+
+		copied := *memory
+		r.store.userMemories[memory.ID] = &copied
+	}
+	return nil
+}
+
+func (r *userMemoryRepository) GetByUserID(userID uint) ([]*entity.UserMemory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var memories []*entity.UserMemory
+	for _, memory := range r.store.userMemories {
+		if memory.UserID == userID {
+			copied := *memory
+			memories = append(memories, &copied)
+		}
+	}
+
+	slices.SortFunc(memories, func(a, b *entity.UserMemory) int {
+		if !a.CreateTime.Equal(b.CreateTime) {
+			return b.CreateTime.Compare(a.CreateTime)
+		}
+		return int(b.ID) - int(a.ID)
+	})
+	return memories, nil
+}
+
+func (r *userMemoryRepository) GetByID(memoryID uint) (*entity.UserMemory, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	memory, exists := r.store.userMemories[memoryID]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	copied := *memory
+	return &copied, nil
+}
+
+func (r *userMemoryRepository) Update(memoryID uint, content string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if memory, exists := r.store.userMemories[memoryID]; exists {
+		memory.Content = content
+		memory.UpdateTime = time.Now()
+	}
+	return nil
+}
+
+func (r *userMemoryRepository) Delete(memoryID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.userMemories, memoryID)
+	return nil
+}
+
+func (r *userMemoryRepository) DeleteByUserID(userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.store.deleteUserMemories(userID)
+	return nil
+}
@@ -0,0 +1,118 @@
+package dao
+
+import (
+	"easy-chat/entity"
+
+	"gorm.io/gorm"
+)
+
+// OrphanReport counts rows whose owning session or user no longer exists
+type OrphanReport struct {
+	ChatHistoryWithoutSession int64
+	ChatHistoryWithoutUser    int64
+	ChatSessionWithoutUser    int64
+	VectorWithoutChatHistory  int64
+
+	DocumentWithoutUser          int64
+	DocumentWithoutSession       int64
+	DocumentChunkWithoutDocument int64
+	VectorWithoutDocumentChunk   int64
+
+	UserMemoryWithoutUser   int64
+	VectorWithoutUserMemory int64
+
+	AssistantWithoutUser int64
+
+	SharedLinkWithoutUser    int64
+	SharedLinkWithoutSession int64
+
+	FeedbackWithoutMessage int64
+
+	AttachmentWithoutUser    int64
+	AttachmentWithoutSession int64
+	// AttachmentBlobKeys are the files of the orphaned attachments, the caller removes them from the blob store
+	AttachmentBlobKeys []string
+
+	ComparisonWithoutMessage int64
+}
+
+// CleanupOrphans finds rows left behind by deletions that were not cascaded
+// and removes them, unless dryRun is set, in which case it only counts them
+func CleanupOrphans(dryRun bool) (*OrphanReport, error) {
+	report := &OrphanReport{}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		sessionIDs := tx.Unscoped().Model(&entity.ChatSession{}).Select("session_id")
+		userIDs := tx.Model(&entity.User{}).Select("id")
+		messageIDs := tx.Model(&entity.ChatHistory{}).Select(castToText(tx, "id"))
+		numericMessageIDs := tx.Model(&entity.ChatHistory{}).Select("id")
+		documentIDs := tx.Model(&entity.Document{}).Select("id")
+		chunkIDs := tx.Model(&entity.DocumentChunk{}).Select(castToText(tx, "id"))
+		userMemoryIDs := tx.Model(&entity.UserMemory{}).Select(castToText(tx, "id"))
+
+		// nothing references the files once their rows are gone, so they are collected first
+		err := tx.Model(&entity.Attachment{}).
+			Where("user_id NOT IN (?) OR session_id NOT IN (?)", userIDs, sessionIDs).
+			Pluck("blob_key", &report.AttachmentBlobKeys).Error
+		if err != nil {
+			return err
+		}
+
+		orphans := []struct {
+			model interface{}
+			query string
+			args  []interface{}
+			count *int64
+		}{
+			{&entity.ChatHistory{}, "session_id NOT IN (?)", []interface{}{sessionIDs}, &report.ChatHistoryWithoutSession},
+			{&entity.ChatHistory{}, "user_id NOT IN (?)", []interface{}{userIDs}, &report.ChatHistoryWithoutUser},
+			{&entity.ChatSession{}, "user_id NOT IN (?)", []interface{}{userIDs}, &report.ChatSessionWithoutUser},
+			{&vectorEntry{}, "collection = ? AND document_id NOT IN (?)", []interface{}{ChatHistoryVectorCollection, messageIDs}, &report.VectorWithoutChatHistory},
+			{&entity.Document{}, "user_id NOT IN (?)", []interface{}{userIDs}, &report.DocumentWithoutUser},
+			{&entity.Document{}, "session_id <> '' AND session_id NOT IN (?)", []interface{}{sessionIDs}, &report.DocumentWithoutSession},
+			{&entity.DocumentChunk{}, "document_id NOT IN (?)", []interface{}{documentIDs}, &report.DocumentChunkWithoutDocument},
+			{&vectorEntry{}, "collection = ? AND document_id NOT IN (?)", []interface{}{DocumentChunkVectorCollection, chunkIDs}, &report.VectorWithoutDocumentChunk},
+			{&entity.UserMemory{}, "user_id NOT IN (?)", []interface{}{userIDs}, &report.UserMemoryWithoutUser},
+			{&vectorEntry{}, "collection = ? AND document_id NOT IN (?)", []interface{}{UserMemoryVectorCollection, userMemoryIDs}, &report.VectorWithoutUserMemory},
+			{&entity.Assistant{}, "user_id NOT IN (?)", []interface{}{userIDs}, &report.AssistantWithoutUser},
+			{&entity.SharedLink{}, "user_id NOT IN (?)", []interface{}{userIDs}, &report.SharedLinkWithoutUser},
+			{&entity.SharedLink{}, "session_id NOT IN (?)", []interface{}{sessionIDs}, &report.SharedLinkWithoutSession},
+			{&entity.MessageFeedback{}, "message_id NOT IN (?)", []interface{}{numericMessageIDs}, &report.FeedbackWithoutMessage},
+			{&entity.Attachment{}, "user_id NOT IN (?)", []interface{}{userIDs}, &report.AttachmentWithoutUser},
+			{&entity.Attachment{}, "session_id NOT IN (?)", []interface{}{sessionIDs}, &report.AttachmentWithoutSession},
+			{&entity.ModelComparison{}, "message_id NOT IN (?)", []interface{}{numericMessageIDs}, &report.ComparisonWithoutMessage},
+		}
+
+		for _, orphan := range orphans {
+			if dryRun {
+				if err := tx.Unscoped().Model(orphan.model).Where(orphan.query, orphan.args...).Count(orphan.count).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			result := tx.Unscoped().Where(orphan.query, orphan.args...).Delete(orphan.model)
+			if result.Error != nil {
+				return result.Error
+			}
+			*orphan.count = result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func castToText(tx *gorm.DB, column string) string {
+	switch tx.Dialector.Name() {
+	case DriverMySQL:
+		return "CAST(" + column + " AS CHAR)"
+	case DriverPostgres:
+		return "CAST(" + column + " AS VARCHAR)"
+	default:
+		return "CAST(" + column + " AS TEXT)"
+	}
+}
@@ -0,0 +1,41 @@
+package dao
+
+import (
+	"easy-chat/entity"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SaveMessageFeedback rates the message, replacing the rating it had
+func SaveMessageFeedback(feedback *entity.MessageFeedback) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rating", "category", "comment", "update_time"}),
+	}).Create(feedback).Error
+}
+
+func GetMessageFeedbackByMessageID(messageID uint) (*entity.MessageFeedback, error) {
+	var feedback entity.MessageFeedback
+	if err := db.Where("message_id = ?", messageID).First(&feedback).Error; err != nil {
+		return nil, err
+	}
+	return &feedback, nil
+}
+
+func DeleteMessageFeedback(messageID uint) error {
+	return db.Where("message_id = ?", messageID).Delete(&entity.MessageFeedback{}).Error
+}
+
+// GetMessageFeedbackBetween returns the ratings given from from until to, oldest first, without comments
+func GetMessageFeedbackBetween(from, to time.Time) ([]*entity.MessageFeedback, error) {
+	var feedback []*entity.MessageFeedback
+	err := db.Omit("Comment").
+		Where("create_time >= ? AND create_time < ?", from, to).
+		Order("create_time, id").
+		Find(&feedback).Error
+	if err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
@@ -0,0 +1,130 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrNoMigrationToRollback = errors.New("no migration to roll back")
+
+type migration struct {
+	Version uint
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// schemaMigration records a migration that has been applied to the database
+type schemaMigration struct {
+	Version     uint      `gorm:"primaryKey;autoIncrement:false"`
+	Name        string    `gorm:"type:varchar(100)"`
+	AppliedTime time.Time `gorm:"autoCreateTime"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migration"
+}
+
+type MigrationStatus struct {
+	Version     uint
+	Name        string
+	Applied     bool
+	AppliedTime time.Time
+}
+
+// MigrateUp applies every pending migration in version order
+func MigrateUp() error {
+	applied, err := getAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, exists := applied[m.Version]; exists {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("applied migration %d %s", m.Version, m.Name)
+	}
+
+	return nil
+}
+
+// MigrateDown rolls back the most recently applied migration
+func MigrateDown() error {
+	applied, err := getAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if _, exists := applied[m.Version]; !exists {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d %s: %w", m.Version, m.Name, err)
+		}
+		log.Printf("rolled back migration %d %s", m.Version, m.Name)
+		return nil
+	}
+
+	return ErrNoMigrationToRollback
+}
+
+func GetMigrationStatuses() ([]MigrationStatus, error) {
+	applied, err := getAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		record, exists := applied[m.Version]
+		statuses[i] = MigrationStatus{
+			Version:     m.Version,
+			Name:        m.Name,
+			Applied:     exists,
+			AppliedTime: record.AppliedTime,
+		}
+	}
+
+	return statuses, nil
+}
+
+func getAppliedMigrations() (map[uint]schemaMigration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[uint]schemaMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
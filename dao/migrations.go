@@ -0,0 +1,634 @@
+package dao
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations lists every schema change in the order it must be applied.
+// Each migration works on its own copy of the table structs, so later
+// changes to package entity never alter what an old migration does.
+// Time columns get no database default, MySQL only accepts one with the
+// precision of its column, which SQLite cannot parse, and the entities
+// set their times on insert anyway.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return createTablesIfNotExist(tx, &userV1{}, &chatSessionV1{}, &chatHistoryV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&chatHistoryV1{}, &chatSessionV1{}, &userV1{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_user_profile_and_token_version",
+		Up: func(tx *gorm.DB) error {
+			return addColumnsIfNotExist(tx, &userV2{}, "DisplayName", "Preferences", "TokenVersion")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &userV2{}, "DisplayName", "Preferences", "TokenVersion")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "add_chat_session_archive_and_trash",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumnsIfNotExist(tx, &chatSessionV3{}, "ArchiveTime", "DeleteTime"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&chatSessionV3{}, "DeleteTime")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndexIfExists(tx, &chatSessionV3{}, "DeleteTime"); err != nil {
+				return err
+			}
+			return dropColumns(tx, &chatSessionV3{}, "ArchiveTime", "DeleteTime")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_chat_session_last_active_time",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumnsIfNotExist(tx, &chatSessionV4{}, "LastActiveTime"); err != nil {
+				return err
+			}
+
+			err := tx.Exec(`UPDATE chat_session SET last_active_time = COALESCE(
+				(SELECT MAX(chat_history.create_time) FROM chat_history WHERE chat_history.session_id = chat_session.session_id),
+				create_time)`).Error
+			if err != nil {
+				return err
+			}
+
+			return tx.Migrator().CreateIndex(&chatSessionV4{}, "LastActiveTime")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndexIfExists(tx, &chatSessionV4{}, "LastActiveTime"); err != nil {
+				return err
+			}
+			return dropColumns(tx, &chatSessionV4{}, "LastActiveTime")
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_chat_history_fulltext_index",
+		// only MySQL gets a FULLTEXT index, the other drivers search with LIKE
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != DriverMySQL {
+				return nil
+			}
+			return tx.Exec("CREATE FULLTEXT INDEX idx_chat_history_content ON chat_history (content) WITH PARSER ngram").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != DriverMySQL {
+				return nil
+			}
+			return tx.Exec("DROP INDEX idx_chat_history_content ON chat_history").Error
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_vector_entry",
+		Up: func(tx *gorm.DB) error {
+			return createTablesIfNotExist(tx, &vectorEntryV6{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&vectorEntryV6{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create_document",
+		Up: func(tx *gorm.DB) error {
+			return createTablesIfNotExist(tx, &documentV7{}, &documentChunkV7{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&documentChunkV7{}, &documentV7{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "add_chat_session_memory",
+		Up: func(tx *gorm.DB) error {
+			return addColumnsIfNotExist(tx, &chatSessionV8{}, "MemoryStrategy", "MemorySummary", "MemorySummaryUntilID")
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &chatSessionV8{}, "MemoryStrategy", "MemorySummary", "MemorySummaryUntilID")
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_user_memory",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumnsIfNotExist(tx, &userV9{}, "MemoryEnabled"); err != nil {
+				return err
+			}
+			return createTablesIfNotExist(tx, &userMemoryV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&userMemoryV9{}); err != nil {
+				return err
+			}
+			return dropColumns(tx, &userV9{}, "MemoryEnabled")
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_assistant",
+		Up: func(tx *gorm.DB) error {
+			if err := createTablesIfNotExist(tx, &assistantV10{}); err != nil {
+				return err
+			}
+			if err := addColumnsIfNotExist(tx, &chatSessionV10{}, "AssistantID"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&chatSessionV10{}, "AssistantID")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropIndexIfExists(tx, &chatSessionV10{}, "AssistantID"); err != nil {
+				return err
+			}
+			if err := dropColumns(tx, &chatSessionV10{}, "AssistantID"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&assistantV10{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add_chat_session_settings",
+		Up: func(tx *gorm.DB) error {
+			return addColumnsIfNotExist(tx, &chatSessionV11{}, chatSessionV12Columns...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &chatSessionV11{}, chatSessionV12Columns...)
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add_chat_history_parent",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumnsIfNotExist(tx, &chatHistoryV12{}, "ParentID"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(&chatHistoryV12{}, "ParentID"); err != nil {
+				return err
+			}
+			if err := addColumnsIfNotExist(tx, &chatSessionV12{}, "ActiveMessageID"); err != nil {
+				return err
+			}
+			return linkChatHistories(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &chatSessionV12{}, "ActiveMessageID"); err != nil {
+				return err
+			}
+			if err := dropIndexIfExists(tx, &chatHistoryV12{}, "ParentID"); err != nil {
+				return err
+			}
+			return dropColumns(tx, &chatHistoryV12{}, "ParentID")
+		},
+	},
+	{
+		Version: 13,
+		Name:    "add_chat_history_metadata",
+		Up: func(tx *gorm.DB) error {
+			return addColumnsIfNotExist(tx, &chatHistoryV13{}, chatHistoryV14Columns...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return dropColumns(tx, &chatHistoryV13{}, chatHistoryV14Columns...)
+		},
+	},
+	{
+		Version: 14,
+		Name:    "create_shared_link",
+		Up: func(tx *gorm.DB) error {
+			return createTablesIfNotExist(tx, &sharedLinkV14{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&sharedLinkV14{})
+		},
+	},
+	{
+		Version: 15,
+		Name:    "create_message_feedback",
+		Up: func(tx *gorm.DB) error {
+			if err := addColumnsIfNotExist(tx, &chatHistoryV15{}, "Mode"); err != nil {
+				return err
+			}
+			return createTablesIfNotExist(tx, &messageFeedbackV15{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&messageFeedbackV15{}); err != nil {
+				return err
+			}
+			return dropColumns(tx, &chatHistoryV15{}, "Mode")
+		},
+	},
+	{
+		Version: 16,
+		Name:    "create_attachment",
+		Up: func(tx *gorm.DB) error {
+			return createTablesIfNotExist(tx, &attachmentV16{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&attachmentV16{})
+		},
+	},
+	{
+		Version: 17,
+		Name:    "create_model_comparison",
+		Up: func(tx *gorm.DB) error {
+			return createTablesIfNotExist(tx, &modelComparisonV17{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&modelComparisonV17{})
+		},
+	},
+}
+
+type userV1 struct {
+	ID         uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time
+	UpDateTime time.Time
+	Username   string    `gorm:"type:varchar(50);not null;unique"`
+	Email      string    `gorm:"type:varchar(100);not null;unique"`
+	Password   string    `gorm:"type:varchar(100);not null"`
+	LastLogin  time.Time `gorm:"default:null"`
+}
+
+func (userV1) TableName() string {
+	return "user"
+}
+
+type chatSessionV1 struct {
+	SessionID   string `gorm:"primaryKey;type:char(36)"`
+	CreateTime  time.Time
+	UpdateTime  time.Time
+	UserID      uint   `gorm:"not null;index"`
+	SessionName string `gorm:"type:varchar(50)"`
+}
+
+func (chatSessionV1) TableName() string {
+	return "chat_session"
+}
+
+type chatHistoryV1 struct {
+	ID          uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime  time.Time
+	UpdateTime  time.Time
+	UserID      uint   `gorm:"not null;index"`
+	SessionID   string `gorm:"type:char(36);not null;index"`
+	MessageType string `gorm:"type:varchar(10);not null"`
+	Content     string `gorm:"type:text"`
+}
+
+func (chatHistoryV1) TableName() string {
+	return "chat_history"
+}
+
+type userV2 struct {
+	DisplayName  string `gorm:"type:varchar(50)"`
+	Preferences  string `gorm:"type:text"`
+	TokenVersion uint   `gorm:"not null;default:0"`
+}
+
+func (userV2) TableName() string {
+	return "user"
+}
+
+type chatSessionV3 struct {
+	ArchiveTime *time.Time
+	DeleteTime  *time.Time `gorm:"index"`
+}
+
+func (chatSessionV3) TableName() string {
+	return "chat_session"
+}
+
+type chatSessionV4 struct {
+	LastActiveTime *time.Time `gorm:"index"`
+}
+
+func (chatSessionV4) TableName() string {
+	return "chat_session"
+}
+
+type vectorEntryV6 struct {
+	Collection string `gorm:"primaryKey;type:varchar(100)"`
+	DocumentID string `gorm:"primaryKey;type:varchar(100)"`
+	CreateTime time.Time
+	UpdateTime time.Time
+	Content    string `gorm:"type:text"`
+	Metadata   string `gorm:"type:text"`
+	Embedding  []byte `gorm:"not null"`
+}
+
+func (vectorEntryV6) TableName() string {
+	return "vector_entry"
+}
+
+type documentV7 struct {
+	ID         uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time
+	UpdateTime time.Time
+	UserID     uint   `gorm:"not null;index"`
+	SessionID  string `gorm:"type:varchar(36);not null;default:'';index"`
+	FileName   string `gorm:"type:varchar(255);not null"`
+	Size       int64  `gorm:"not null"`
+	ChunkCount int    `gorm:"not null"`
+}
+
+func (documentV7) TableName() string {
+	return "document"
+}
+
+type documentChunkV7 struct {
+	ID         uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time
+	DocumentID uint   `gorm:"not null;index"`
+	ChunkIndex int    `gorm:"not null"`
+	Content    string `gorm:"type:text"`
+}
+
+func (documentChunkV7) TableName() string {
+	return "document_chunk"
+}
+
+type chatSessionV8 struct {
+	MemoryStrategy       string `gorm:"type:varchar(20);not null;default:''"`
+	MemorySummary        string `gorm:"type:text"`
+	MemorySummaryUntilID uint   `gorm:"not null;default:0"`
+}
+
+func (chatSessionV8) TableName() string {
+	return "chat_session"
+}
+
+type userV9 struct {
+	MemoryEnabled bool `gorm:"not null;default:true"`
+}
+
+func (userV9) TableName() string {
+	return "user"
+}
+
+type userMemoryV9 struct {
+	ID         uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time
+	UpdateTime time.Time
+	UserID     uint   `gorm:"not null;index"`
+	Content    string `gorm:"type:varchar(500);not null"`
+	SessionID  string `gorm:"type:varchar(36);not null;default:''"`
+}
+
+func (userMemoryV9) TableName() string {
+	return "user_memory"
+}
+
+type assistantV10 struct {
+	ID           uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime   time.Time
+	UpdateTime   time.Time
+	UserID       uint   `gorm:"not null;index"`
+	Name         string `gorm:"type:varchar(50);not null"`
+	SystemPrompt string `gorm:"type:text"`
+	Model        string `gorm:"type:varchar(50);not null;default:''"`
+	Mode         string `gorm:"type:varchar(20);not null;default:''"`
+	Temperature  *float64
+	TopP         *float64
+	MaxTokens    int    `gorm:"not null;default:0"`
+	Tools        string `gorm:"type:varchar(255);not null;default:''"`
+}
+
+func (assistantV10) TableName() string {
+	return "assistant"
+}
+
+type chatSessionV10 struct {
+	AssistantID uint `gorm:"not null;default:0;index"`
+}
+
+func (chatSessionV10) TableName() string {
+	return "chat_session"
+}
+
+type chatSessionV11 struct {
+	Model       string `gorm:"type:varchar(50);not null;default:''"`
+	Mode        string `gorm:"type:varchar(20);not null;default:''"`
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int    `gorm:"not null;default:0"`
+	Tools       string `gorm:"type:varchar(255);not null;default:''"`
+}
+
+func (chatSessionV11) TableName() string {
+	return "chat_session"
+}
+
+var chatSessionV12Columns = []string{"Model", "Mode", "Temperature", "TopP", "MaxTokens", "Tools"}
+
+type chatHistoryV12 struct {
+	ParentID uint `gorm:"not null;default:0;index"`
+}
+
+func (chatHistoryV12) TableName() string {
+	return "chat_history"
+}
+
+type chatSessionV12 struct {
+	ActiveMessageID uint `gorm:"not null;default:0"`
+}
+
+func (chatSessionV12) TableName() string {
+	return "chat_session"
+}
+
+type chatHistoryV13 struct {
+	Model            string `gorm:"type:varchar(50);not null;default:''"`
+	PromptTokens     int    `gorm:"not null;default:0"`
+	CompletionTokens int    `gorm:"not null;default:0"`
+	Trace            string `gorm:"type:text"`
+}
+
+func (chatHistoryV13) TableName() string {
+	return "chat_history"
+}
+
+var chatHistoryV14Columns = []string{"Model", "PromptTokens", "CompletionTokens", "Trace"}
+
+type sharedLinkV14 struct {
+	ID         uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time
+	UserID     uint   `gorm:"not null;index"`
+	SessionID  string `gorm:"type:varchar(36);not null;index"`
+	Token      string `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Title      string `gorm:"type:varchar(50);not null;default:''"`
+	Scope      string `gorm:"type:varchar(10);not null"`
+	Snapshot   string
+	ExpireTime *time.Time
+}
+
+func (sharedLinkV14) TableName() string {
+	return "shared_link"
+}
+
+type chatHistoryV15 struct {
+	Mode string `gorm:"type:varchar(20);not null;default:''"`
+}
+
+func (chatHistoryV15) TableName() string {
+	return "chat_history"
+}
+
+type messageFeedbackV15 struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"index"`
+	UpdateTime time.Time
+	UserID     uint   `gorm:"not null;index"`
+	SessionID  string `gorm:"type:varchar(36);not null;index"`
+	MessageID  uint   `gorm:"not null;uniqueIndex"`
+	Rating     string `gorm:"type:varchar(10);not null"`
+	Category   string `gorm:"type:varchar(30);not null;default:''"`
+	Comment    string `gorm:"type:varchar(1000);not null;default:''"`
+	Model      string `gorm:"type:varchar(50);not null;default:''"`
+	Mode       string `gorm:"type:varchar(20);not null;default:''"`
+	Tools      string `gorm:"type:varchar(255);not null;default:''"`
+}
+
+func (messageFeedbackV15) TableName() string {
+	return "message_feedback"
+}
+
+type attachmentV16 struct {
+	ID          uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime  time.Time
+	UserID      uint   `gorm:"not null;index"`
+	SessionID   string `gorm:"type:varchar(36);not null;index"`
+	MessageID   uint   `gorm:"not null;default:0;index"`
+	FileName    string `gorm:"type:varchar(255);not null"`
+	ContentType string `gorm:"type:varchar(100);not null"`
+	Kind        string `gorm:"type:varchar(10);not null"`
+	Size        int64  `gorm:"not null"`
+	BlobKey     string `gorm:"type:varchar(255);not null;uniqueIndex"`
+}
+
+func (attachmentV16) TableName() string {
+	return "attachment"
+}
+
+type modelComparisonV17 struct {
+	ID                 uint `gorm:"primaryKey;autoIncrement"`
+	CreateTime         time.Time
+	UpdateTime         time.Time
+	UserID             uint   `gorm:"not null;index"`
+	SessionID          string `gorm:"type:varchar(36);not null;index"`
+	MessageID          uint   `gorm:"not null;uniqueIndex"`
+	Models             string `gorm:"type:varchar(255);not null"`
+	PreferredMessageID uint   `gorm:"not null;default:0"`
+	PreferredModel     string `gorm:"type:varchar(50);not null;default:''"`
+}
+
+func (modelComparisonV17) TableName() string {
+	return "model_comparison"
+}
+
+const migrationBatchSize = 500
+
+// createTablesIfNotExist leaves tables that were created by hand before migrations existed untouched
+func createTablesIfNotExist(tx *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		if tx.Migrator().HasTable(model) {
+			continue
+		}
+		if err := tx.Migrator().CreateTable(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func addColumnsIfNotExist(tx *gorm.DB, model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if tx.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(model, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropIndexIfExists tolerates the index being gone already, which happens on SQLite
+// where dropping a column rebuilds the table without its indexes
+func dropIndexIfExists(tx *gorm.DB, model interface{}, field string) error {
+	if !tx.Migrator().HasIndex(model, field) {
+		return nil
+	}
+	return tx.Migrator().DropIndex(model, field)
+}
+
+func dropColumns(tx *gorm.DB, model interface{}, fields ...string) error {
+	for _, field := range fields {
+		if !tx.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := tx.Migrator().DropColumn(model, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkChatHistories turns the flat history of every session into a single branch,
+// each message following the one before it and the last one being active
+func linkChatHistories(tx *gorm.DB) error {
+	var sessionID string
+	var parentID uint
+	activate := func() error {
+		if sessionID == "" {
+			return nil
+		}
+		return tx.Table("chat_session").Where("session_id = ?", sessionID).Update("active_message_id", parentID).Error
+	}
+
+	for offset := 0; ; offset += migrationBatchSize {
+		var rows []struct {
+			ID        uint
+			SessionID string
+		}
+		err := tx.Table("chat_history").
+			Select("id, session_id").
+			Order("session_id, create_time, id").
+			Offset(offset).Limit(migrationBatchSize).
+			Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if row.SessionID != sessionID {
+				if err := activate(); err != nil {
+					return err
+				}
+				sessionID, parentID = row.SessionID, 0
+			}
+
+			if parentID != 0 {
+				if err := tx.Table("chat_history").Where("id = ?", row.ID).Update("parent_id", parentID).Error; err != nil {
+					return err
+				}
+			}
+			parentID = row.ID
+		}
+
+		if len(rows) < migrationBatchSize {
+			return activate()
+		}
+	}
+}
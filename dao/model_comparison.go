@@ -0,0 +1,33 @@
+package dao
+
+import (
+	"easy-chat/entity"
+)
+
+func CreateModelComparison(comparison *entity.ModelComparison) error {
+	return db.Create(comparison).Error
+}
+
+func GetModelComparisonByMessageID(messageID uint) (*entity.ModelComparison, error) {
+	var comparison entity.ModelComparison
+	if err := db.Where("message_id = ?", messageID).First(&comparison).Error; err != nil {
+		return nil, err
+	}
+	return &comparison, nil
+}
+
+// SetModelComparisonPreference records the preferred answer, replacing an earlier preference
+func SetModelComparisonPreference(comparisonID, messageID uint, model string) error {
+	return db.Model(&entity.ModelComparison{ID: comparisonID}).Updates(map[string]interface{}{
+		"preferred_message_id": messageID,
+		"preferred_model":      model,
+	}).Error
+}
+
+func GetPreferredModelComparisons() ([]*entity.ModelComparison, error) {
+	var comparisons []*entity.ModelComparison
+	if err := db.Where("preferred_message_id <> 0").Order("id").Find(&comparisons).Error; err != nil {
+		return nil, err
+	}
+	return comparisons, nil
+}
@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrCursorNotFound = errors.New("cursor not found")
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// HistoryPage selects messages older than BeforeID or newer than AfterID.
+// Without a cursor the most recent messages are returned.
+type HistoryPage struct {
+	BeforeID uint
+	AfterID  uint
+	Limit    int
+}
+
+// SessionPage selects sessions that were last active before the session BeforeID
+type SessionPage struct {
+	BeforeID string
+	Limit    int
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageLimit
+	}
+	if limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return limit
+}
+
+// Apply returns the page of the message IDs, which are oldest first,
+// and whether more messages exist in the paging direction
+func (p HistoryPage) Apply(messageIDs []uint) ([]uint, bool, error) {
+	limit := normalizeLimit(p.Limit)
+
+	indexOf := func(id uint) (int, error) {
+		index := slices.Index(messageIDs, id)
+		if index < 0 {
+			return 0, fmt.Errorf("%w: %d", ErrCursorNotFound, id)
+		}
+		return index, nil
+	}
+
+	switch {
+	case p.AfterID != 0:
+		index, err := indexOf(p.AfterID)
+		if err != nil {
+			return nil, false, err
+		}
+		end := min(index+1+limit, len(messageIDs))
+		return messageIDs[index+1 : end], end < len(messageIDs), nil
+	case p.BeforeID != 0:
+		index, err := indexOf(p.BeforeID)
+		if err != nil {
+			return nil, false, err
+		}
+		start := max(index-limit, 0)
+		return messageIDs[start:index], start > 0, nil
+	default:
+		start := max(len(messageIDs)-limit, 0)
+		return messageIDs[start:], start > 0, nil
+	}
+}
@@ -0,0 +1,40 @@
+package dao
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestHistoryPageApply(t *testing.T) {
+	ids := []uint{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name        string
+		page        HistoryPage
+		want        []uint
+		wantHasMore bool
+		wantErr     error
+	}{
+		{"latest", HistoryPage{Limit: 2}, []uint{4, 5}, true, nil},
+		{"all", HistoryPage{}, ids, false, nil},
+		{"before", HistoryPage{BeforeID: 4, Limit: 2}, []uint{2, 3}, true, nil},
+		{"before first page", HistoryPage{BeforeID: 3, Limit: 2}, []uint{1, 2}, false, nil},
+		{"after", HistoryPage{AfterID: 1, Limit: 2}, []uint{2, 3}, true, nil},
+		{"after last page", HistoryPage{AfterID: 3, Limit: 2}, []uint{4, 5}, false, nil},
+		{"limit above max", HistoryPage{Limit: MaxPageLimit + 1}, ids, false, nil},
+		{"unknown cursor", HistoryPage{BeforeID: 9}, nil, false, ErrCursorNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, hasMore, err := tt.page.Apply(ids)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) || hasMore != tt.wantHasMore {
+				t.Errorf("Apply() = %v, %v, want %v, %v", got, hasMore, tt.want, tt.wantHasMore)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package dao
+
+import (
+	"easy-chat/entity"
+	"easy-chat/request"
+	"time"
+)
+
+type UserRepository interface {
+	Create(request *request.UserRegisterRequest) (*entity.User, error)
+	GetByUsername(username string) (*entity.User, error)
+	Update(user *entity.User) error
+	Delete(userID uint) error
+	CheckEmailExists(email string, excludeUserID uint) error
+}
+
+type SessionRepository interface {
+	// Create saves a session of the user with the name and settings of session, its ID and owner are filled in
+	Create(username string, session *entity.ChatSession) (string, error)
+	GetByID(sessionID string) (*entity.ChatSession, error)
+	GetByUsername(username string, status string, page SessionPage) ([]*entity.ChatSession, bool, error)
+	Rename(sessionID, sessionName string) error
+	SetMemoryStrategy(sessionID, strategy string) error
+	SetAssistant(sessionID string, assistantID uint) error
+	UpdateSettings(sessionID string, settings entity.ChatSettings) error
+	SetActiveMessage(sessionID string, messageID uint) error
+	SaveSummary(sessionID, summary string, untilID uint) error
+	Archive(sessionID string, archived bool) error
+	Trash(sessionID string) error
+	Restore(sessionID string) error
+	Delete(sessionID string) error
+	PurgeTrashed(before time.Time) ([]string, error)
+}
+
+type HistoryRepository interface {
+	// GetBySessionID returns the messages of every branch of the session, oldest first
+	GetBySessionID(sessionID string) ([]*entity.ChatHistory, error)
+	// GetTree returns the messages of the session like GetBySessionID with only their ID and ParentID
+	GetTree(sessionID string) ([]*entity.ChatHistory, error)
+	GetByIDs(ids []uint) ([]*entity.ChatHistory, error)
+	Save(chatRequest *request.ChatRequest, chatHistories []*entity.ChatHistory) ([]*entity.ChatHistory, error)
+	Search(filter *SearchFilter) ([]*SearchResult, error)
+}
+
+type DocumentRepository interface {
+	Create(document *entity.Document, chunks []*entity.DocumentChunk) error
+	GetByID(documentID uint) (*entity.Document, error)
+	GetByUserID(userID uint, sessionID string) ([]*entity.Document, error)
+	GetChunksByIDs(ids []uint) ([]*entity.DocumentChunk, error)
+	Delete(documentID uint) error
+}
+
+type UserMemoryRepository interface {
+	Create(memories []*entity.UserMemory) error
+	GetByUserID(userID uint) ([]*entity.UserMemory, error)
+	GetByID(memoryID uint) (*entity.UserMemory, error)
+	Update(memoryID uint, content string) error
+	Delete(memoryID uint) error
+	DeleteByUserID(userID uint) error
+}
+
+type AssistantRepository interface {
+	Create(assistant *entity.Assistant) error
+	GetByID(assistantID uint) (*entity.Assistant, error)
+	GetByUserID(userID uint) ([]*entity.Assistant, error)
+	Update(assistant *entity.Assistant) error
+	Delete(assistantID uint) error
+}
+
+type FeedbackRepository interface {
+	// Save replaces the rating the message had
+	Save(feedback *entity.MessageFeedback) error
+	GetByMessageID(messageID uint) (*entity.MessageFeedback, error)
+	Delete(messageID uint) error
+	// GetBetween returns the ratings given in [from, to) without their comments, oldest first
+	GetBetween(from, to time.Time) ([]*entity.MessageFeedback, error)
+}
+
+type AttachmentRepository interface {
+	Create(attachment *entity.Attachment) error
+	GetByIDs(ids []uint) ([]*entity.Attachment, error)
+	GetByMessageIDs(messageIDs []uint) ([]*entity.Attachment, error)
+	GetByUserID(userID uint) ([]*entity.Attachment, error)
+	// AttachToMessage links the files to the user message they were sent with
+	AttachToMessage(ids []uint, messageID uint) error
+	// GetUnsentBefore returns the files uploaded before the time that were never sent
+	GetUnsentBefore(before time.Time) ([]*entity.Attachment, error)
+	Delete(attachmentID uint) error
+}
+
+type ComparisonRepository interface {
+	Create(comparison *entity.ModelComparison) error
+	// GetByMessageID returns the comparison of the answers to the question
+	GetByMessageID(messageID uint) (*entity.ModelComparison, error)
+	SetPreference(comparisonID, messageID uint, model string) error
+	// GetPreferred returns the comparisons in which the user preferred an answer
+	GetPreferred() ([]*entity.ModelComparison, error)
+}
+
+type SharedLinkRepository interface {
+	Create(sharedLink *entity.SharedLink) error
+	GetByID(sharedLinkID uint) (*entity.SharedLink, error)
+	GetByToken(token string) (*entity.SharedLink, error)
+	// GetByUserID leaves the snapshots out
+	GetByUserID(userID uint) ([]*entity.SharedLink, error)
+	Delete(sharedLinkID uint) error
+}
+
+type Repositories struct {
+	Users        UserRepository
+	Sessions     SessionRepository
+	Histories    HistoryRepository
+	Documents    DocumentRepository
+	UserMemories UserMemoryRepository
+	Assistants   AssistantRepository
+	SharedLinks  SharedLinkRepository
+	Feedback     FeedbackRepository
+	Attachments  AttachmentRepository
+	Comparisons  ComparisonRepository
+}
+
+// NewRepositories returns repositories backed by the database opened in Init
+func NewRepositories() *Repositories {
+	return &Repositories{
+		Users:        userRepository{},
+		Sessions:     sessionRepository{},
+		Histories:    historyRepository{},
+		Documents:    documentRepository{},
+		UserMemories: userMemoryRepository{},
+		Assistants:   assistantRepository{},
+		SharedLinks:  sharedLinkRepository{},
+		Feedback:     feedbackRepository{},
+		Attachments:  attachmentRepository{},
+		Comparisons:  comparisonRepository{},
+	}
+}
+
+type userRepository struct{}
+
+func (userRepository) Create(request *request.UserRegisterRequest) (*entity.User, error) {
+	return CreateUser(request)
+}
+
+func (userRepository) GetByUsername(username string) (*entity.User, error) {
+	return GetUserByUsername(username)
+}
+
+func (userRepository) Update(user *entity.User) error {
+	return UpdateUser(user)
+}
+
+func (userRepository) Delete(userID uint) error {
+	return DeleteUser(userID)
+}
+
+func (userRepository) CheckEmailExists(email string, excludeUserID uint) error {
+	return CheckEmailExists(email, excludeUserID)
+}
+
+type sessionRepository struct{}
+
+func (sessionRepository) Create(username string, session *entity.ChatSession) (string, error) {
+	return CreateChatSession(username, session)
+}
+
+func (sessionRepository) GetByID(sessionID string) (*entity.ChatSession, error) {
+	return GetChatSessionByID(sessionID)
+}
+
+func (sessionRepository) GetByUsername(username string, status string, page SessionPage) ([]*entity.ChatSession, bool, error) {
+	return GetChatSessionByUsername(username, status, page)
+}
+
+func (sessionRepository) Rename(sessionID, sessionName string) error {
+	return RenameChatSession(sessionID, sessionName)
+}
+
+func (sessionRepository) SetMemoryStrategy(sessionID, strategy string) error {
+	return SetChatSessionMemoryStrategy(sessionID, strategy)
+}
+
+func (sessionRepository) SetAssistant(sessionID string, assistantID uint) error {
+	return SetChatSessionAssistant(sessionID, assistantID)
+}
+
+func (sessionRepository) UpdateSettings(sessionID string, settings entity.ChatSettings) error {
+	return UpdateChatSessionSettings(sessionID, settings)
+}
+
+func (sessionRepository) SetActiveMessage(sessionID string, messageID uint) error {
+	return SetChatSessionActiveMessage(sessionID, messageID)
+}
+
+func (sessionRepository) SaveSummary(sessionID, summary string, untilID uint) error {
+	return SaveChatSessionSummary(sessionID, summary, untilID)
+}
+
+func (sessionRepository) Archive(sessionID string, archived bool) error {
+	return ArchiveChatSession(sessionID, archived)
+}
+
+func (sessionRepository) Trash(sessionID string) error {
+	return TrashChatSession(sessionID)
+}
+
+func (sessionRepository) Restore(sessionID string) error {
+	return RestoreChatSession(sessionID)
+}
+
+func (sessionRepository) Delete(sessionID string) error {
+	return DeleteChatSession(sessionID)
+}
+
+func (sessionRepository) PurgeTrashed(before time.Time) ([]string, error) {
+	return PurgeTrashedChatSessions(before)
+}
+
+type historyRepository struct{}
+
+func (historyRepository) GetBySessionID(sessionID string) ([]*entity.ChatHistory, error) {
+	return GetChatHistoryBySessionID(sessionID)
+}
+
+func (historyRepository) GetTree(sessionID string) ([]*entity.ChatHistory, error) {
+	return GetChatHistoryTree(sessionID)
+}
+
+func (historyRepository) GetByIDs(ids []uint) ([]*entity.ChatHistory, error) {
+	return GetChatHistoryByIDs(ids)
+}
+
+func (historyRepository) Save(chatRequest *request.ChatRequest, chatHistories []*entity.ChatHistory) ([]*entity.ChatHistory, error) {
+	return SaveChatHistory(chatRequest, chatHistories)
+}
+
+func (historyRepository) Search(filter *SearchFilter) ([]*SearchResult, error) {
+	return SearchChatHistory(filter)
+}
+
+type documentRepository struct{}
+
+func (documentRepository) Create(document *entity.Document, chunks []*entity.DocumentChunk) error {
+	return CreateDocument(document, chunks)
+}
+
+func (documentRepository) GetByID(documentID uint) (*entity.Document, error) {
+	return GetDocumentByID(documentID)
+}
+
+func (documentRepository) GetByUserID(userID uint, sessionID string) ([]*entity.Document, error) {
+	return GetDocumentsByUserID(userID, sessionID)
+}
+
+func (documentRepository) GetChunksByIDs(ids []uint) ([]*entity.DocumentChunk, error) {
+	return GetDocumentChunksByIDs(ids)
+}
+
+func (documentRepository) Delete(documentID uint) error {
+	return DeleteDocument(documentID)
+}
+
+type userMemoryRepository struct{}
+
+func (userMemoryRepository) Create(memories []*entity.UserMemory) error {
+	return CreateUserMemories(memories)
+}
+
+func (userMemoryRepository) GetByUserID(userID uint) ([]*entity.UserMemory, error) {
+	return GetUserMemories(userID)
+}
+
+func (userMemoryRepository) GetByID(memoryID uint) (*entity.UserMemory, error) {
+	return GetUserMemoryByID(memoryID)
+}
+
+func (userMemoryRepository) Update(memoryID uint, content string) error {
+	return UpdateUserMemory(memoryID, content)
+}
+
+func (userMemoryRepository) Delete(memoryID uint) error {
+	return DeleteUserMemory(memoryID)
+}
+
+func (userMemoryRepository) DeleteByUserID(userID uint) error {
+	return DeleteUserMemories(userID)
+}
+
+type assistantRepository struct{}
+
+func (assistantRepository) Create(assistant *entity.Assistant) error {
+	return CreateAssistant(assistant)
+}
+
+func (assistantRepository) GetByID(assistantID uint) (*entity.Assistant, error) {
+	return GetAssistantByID(assistantID)
+}
+
+func (assistantRepository) GetByUserID(userID uint) ([]*entity.Assistant, error) {
+	return GetAssistantsByUserID(userID)
+}
+
+func (assistantRepository) Update(assistant *entity.Assistant) error {
+	return UpdateAssistant(assistant)
+}
+
+func (assistantRepository) Delete(assistantID uint) error {
+	return DeleteAssistant(assistantID)
+}
+
+type sharedLinkRepository struct{}
+
+func (sharedLinkRepository) Create(sharedLink *entity.SharedLink) error {
+	return CreateSharedLink(sharedLink)
+}
+
+func (sharedLinkRepository) GetByID(sharedLinkID uint) (*entity.SharedLink, error) {
+	return GetSharedLinkByID(sharedLinkID)
+}
+
+func (sharedLinkRepository) GetByToken(token string) (*entity.SharedLink, error) {
+	return GetSharedLinkByToken(token)
+}
+
+func (sharedLinkRepository) GetByUserID(userID uint) ([]*entity.SharedLink, error) {
+	return GetSharedLinksByUserID(userID)
+}
+
+func (sharedLinkRepository) Delete(sharedLinkID uint) error {
+	return DeleteSharedLink(sharedLinkID)
+}
+
+type feedbackRepository struct{}
+
+func (feedbackRepository) Save(feedback *entity.MessageFeedback) error {
+	return SaveMessageFeedback(feedback)
+}
+
+func (feedbackRepository) GetByMessageID(messageID uint) (*entity.MessageFeedback, error) {
+	return GetMessageFeedbackByMessageID(messageID)
+}
+
+func (feedbackRepository) Delete(messageID uint) error {
+	return DeleteMessageFeedback(messageID)
+}
+
+func (feedbackRepository) GetBetween(from, to time.Time) ([]*entity.MessageFeedback, error) {
+	return GetMessageFeedbackBetween(from, to)
+}
+
+type attachmentRepository struct{}
+
+func (attachmentRepository) Create(attachment *entity.Attachment) error {
+	return CreateAttachment(attachment)
+}
+
+func (attachmentRepository) GetByIDs(ids []uint) ([]*entity.Attachment, error) {
+	return GetAttachmentsByIDs(ids)
+}
+
+func (attachmentRepository) GetByMessageIDs(messageIDs []uint) ([]*entity.Attachment, error) {
+	return GetAttachmentsByMessageIDs(messageIDs)
+}
+
+func (attachmentRepository) GetByUserID(userID uint) ([]*entity.Attachment, error) {
+	return GetAttachmentsByUserID(userID)
+}
+
+func (attachmentRepository) AttachToMessage(ids []uint, messageID uint) error {
+	return AttachToMessage(ids, messageID)
+}
+
+func (attachmentRepository) GetUnsentBefore(before time.Time) ([]*entity.Attachment, error) {
+	return GetUnsentAttachmentsBefore(before)
+}
+
+func (attachmentRepository) Delete(attachmentID uint) error {
+	return DeleteAttachment(attachmentID)
+}
+
+type comparisonRepository struct{}
+
+func (comparisonRepository) Create(comparison *entity.ModelComparison) error {
+	return CreateModelComparison(comparison)
+}
+
+func (comparisonRepository) GetByMessageID(messageID uint) (*entity.ModelComparison, error) {
+	return GetModelComparisonByMessageID(messageID)
+}
+
+func (comparisonRepository) SetPreference(comparisonID, messageID uint, model string) error {
+	return SetModelComparisonPreference(comparisonID, messageID, model)
+}
+
+func (comparisonRepository) GetPreferred() ([]*entity.ModelComparison, error) {
+	return GetPreferredModelComparisons()
+}
@@ -0,0 +1,76 @@
+package dao
+
+import (
+	"easy-chat/entity"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+type SearchFilter struct {
+	UserID    uint
+	Terms     []string
+	SessionID string
+	Role      string
+	From      time.Time
+	To        time.Time
+	Limit     int
+}
+
+type SearchResult struct {
+	entity.ChatHistory
+	SessionName string
+}
+
+// likeEscaper escapes LIKE wildcards with '!', which unlike backslash means the same on every driver
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// SearchChatHistory finds the user's messages that contain the search terms.
+// MySQL uses the FULLTEXT index on chat_history.content and ranks by relevance,
+// the other drivers fall back to LIKE and return the newest matches first.
+func SearchChatHistory(filter *SearchFilter) ([]*SearchResult, error) {
+	query := db.Table("chat_history").
+		Select("chat_history.*, chat_session.session_name").
+		Joins("JOIN chat_session ON chat_session.session_id = chat_history.session_id").
+		Where("chat_history.user_id = ? AND chat_session.delete_time IS NULL", filter.UserID)
+
+	if filter.SessionID != "" {
+		query = query.Where("chat_history.session_id = ?", filter.SessionID)
+	}
+	if filter.Role != "" {
+		query = query.Where("chat_history.message_type = ?", filter.Role)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("chat_history.create_time >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("chat_history.create_time < ?", filter.To)
+	}
+
+	switch db.Dialector.Name() {
+	case DriverMySQL:
+		match := "MATCH(chat_history.content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+		text := strings.Join(filter.Terms, " ")
+		query = query.Where(match, text).
+			Order(clause.OrderBy{Expression: clause.Expr{SQL: match + " DESC", Vars: []interface{}{text}}}).
+			Order("chat_history.create_time DESC")
+	case DriverPostgres:
+		for _, term := range filter.Terms {
+			query = query.Where("chat_history.content ILIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(term)+"%")
+		}
+		query = query.Order("chat_history.create_time DESC")
+	default:
+		for _, term := range filter.Terms {
+			query = query.Where("chat_history.content LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(term)+"%")
+		}
+		query = query.Order("chat_history.create_time DESC")
+	}
+
+	var results []*SearchResult
+	if err := query.Limit(normalizeLimit(filter.Limit)).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
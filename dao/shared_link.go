@@ -0,0 +1,39 @@
+package dao
+
+import (
+	"easy-chat/entity"
+)
+
+func CreateSharedLink(sharedLink *entity.SharedLink) error {
+	return db.Create(sharedLink).Error
+}
+
+func GetSharedLinkByID(sharedLinkID uint) (*entity.SharedLink, error) {
+	var sharedLink entity.SharedLink
+	if err := db.First(&sharedLink, sharedLinkID).Error; err != nil {
+		return nil, err
+	}
+	return &sharedLink, nil
+}
+
+func GetSharedLinkByToken(token string) (*entity.SharedLink, error) {
+	var sharedLink entity.SharedLink
+	if err := db.Where("token = ?", token).First(&sharedLink).Error; err != nil {
+		return nil, err
+	}
+	return &sharedLink, nil
+}
+
+// GetSharedLinksByUserID returns the user's links without their snapshots, newest first
+func GetSharedLinksByUserID(userID uint) ([]*entity.SharedLink, error) {
+	var sharedLinks []*entity.SharedLink
+	err := db.Omit("Snapshot").Where("user_id = ?", userID).Order("id DESC").Find(&sharedLinks).Error
+	if err != nil {
+		return nil, err
+	}
+	return sharedLinks, nil
+}
+
+func DeleteSharedLink(sharedLinkID uint) error {
+	return db.Delete(&entity.SharedLink{}, sharedLinkID).Error
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 var (
@@ -24,9 +25,10 @@ func CreateUser(request *request.UserRegisterRequest) (*entity.User, error) {
 	}
 
 	user := &entity.User{
-		Username: request.Username,
-		Email:    request.Email,
-		Password: string(passwordHash),
+		Username:      request.Username,
+		Email:         request.Email,
+		Password:      string(passwordHash),
+		MemoryEnabled: true,
 	}
 	result := db.Create(user)
 	if result.Error != nil {
@@ -51,6 +53,48 @@ func UpdateUser(user *entity.User) error {
 	return nil
 }
 
+func CheckEmailExists(email string, excludeUserID uint) error {
+	var user entity.User
+	if result := db.Where("email = ? AND id <> ?", email, excludeUserID).First(&user); result.Error == nil {
+		return fmt.Errorf("%w: %s", ErrEmailAlreadyExists, email)
+	}
+	return nil
+}
+
+// DeleteUser removes the user together with every session and message they own
+func DeleteUser(userID uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.ChatHistory{}).Error; err != nil {
+			return err
+		}
+		if err := deleteDocuments(tx, tx.Where("user_id = ?", userID)); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.UserMemory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.Assistant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.SharedLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.MessageFeedback{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&entity.ModelComparison{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&entity.ChatSession{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&entity.User{}, userID).Error
+	})
+}
+
 func checkUserExists(request *request.UserRegisterRequest) error {
 	var user entity.User
 
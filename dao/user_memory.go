@@ -0,0 +1,39 @@
+package dao
+
+import "easy-chat/entity"
+
+func CreateUserMemories(memories []*entity.UserMemory) error {
+	if len(memories) == 0 {
+		return nil
+	}
+	return db.Create(&memories).Error
+}
+
+// GetUserMemories returns the user's memories, newest first
+func GetUserMemories(userID uint) ([]*entity.UserMemory, error) {
+	var memories []*entity.UserMemory
+	if err := db.Where("user_id = ?", userID).Order("create_time DESC, id DESC").Find(&memories).Error; err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+func GetUserMemoryByID(memoryID uint) (*entity.UserMemory, error) {
+	var memory entity.UserMemory
+	if err := db.First(&memory, memoryID).Error; err != nil {
+		return nil, err
+	}
+	return &memory, nil
+}
+
+func UpdateUserMemory(memoryID uint, content string) error {
+	return db.Model(&entity.UserMemory{}).Where("id = ?", memoryID).Update("content", content).Error
+}
+
+func DeleteUserMemory(memoryID uint) error {
+	return db.Delete(&entity.UserMemory{}, memoryID).Error
+}
+
+func DeleteUserMemories(userID uint) error {
+	return db.Where("user_id = ?", userID).Delete(&entity.UserMemory{}).Error
+}
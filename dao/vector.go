@@ -0,0 +1,22 @@
+package dao
+
+const (
+	// ChatHistoryVectorCollection is the vector store collection that holds message embeddings,
+	// its document IDs are chat_history IDs
+	ChatHistoryVectorCollection = "chat_history"
+	// DocumentChunkVectorCollection holds the embeddings of uploaded documents,
+	// its document IDs are document_chunk IDs
+	DocumentChunkVectorCollection = "document_chunk"
+	// UserMemoryVectorCollection holds the embeddings of user memories, its document IDs are user_memory IDs
+	UserMemoryVectorCollection = "user_memory"
+)
+
+// vectorEntry maps the table of agents/vectorstores/sqlstore for maintenance only
+type vectorEntry struct {
+	Collection string `gorm:"primaryKey"`
+	DocumentID string `gorm:"primaryKey"`
+}
+
+func (vectorEntry) TableName() string {
+	return "vector_entry"
+}
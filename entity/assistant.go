@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// Assistant is a reusable chat configuration a user can bind sessions to
+type Assistant struct {
+	ID           uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime   time.Time `gorm:"autoCreateTime"`
+	UpdateTime   time.Time `gorm:"autoUpdateTime"`
+	UserID       uint      `gorm:"not null;index"`
+	Name         string    `gorm:"type:varchar(50);not null"`
+	SystemPrompt string    `gorm:"type:text"`
+	// ChatSettings apply to the sessions bound to the assistant where they set nothing themselves
+	ChatSettings
+}
+
+func (Assistant) TableName() string {
+	return "assistant"
+}
@@ -0,0 +1,28 @@
+package entity
+
+import "time"
+
+const (
+	AttachmentKindImage = "image"
+	AttachmentKindText  = "text"
+)
+
+// Attachment is a file uploaded to a session to be sent with a query, its content is kept in the blob store
+type Attachment struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"autoCreateTime"`
+	UserID     uint      `gorm:"not null;index"`
+	SessionID  string    `gorm:"type:varchar(36);not null;index"`
+	// MessageID is the user message the file was sent with, 0 until it is sent
+	MessageID   uint   `gorm:"not null;default:0;index"`
+	FileName    string `gorm:"type:varchar(255);not null"`
+	ContentType string `gorm:"type:varchar(100);not null"`
+	// Kind is image for files passed to the model as images, text for files put into the prompt
+	Kind    string `gorm:"type:varchar(10);not null"`
+	Size    int64  `gorm:"not null"`
+	BlobKey string `gorm:"type:varchar(255);not null;uniqueIndex"`
+}
+
+func (Attachment) TableName() string {
+	return "attachment"
+}
@@ -4,12 +4,22 @@ import "time"
 
 type ChatHistory struct {
 	ID          uint      `gorm:"primaryKey;autoIncrement"`
-	CreateTime  time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP"`
-	UpdateTime  time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP;update:CURRENT_TIMESTAMP"`
-	UserID      uint      `gorm:"not_null;index"`
-	SessionID   string    `gorm:"type:char(36);not_null;index"`
-	MessageType string    `gorm:"type:varchar(10);not_null"`
+	CreateTime  time.Time `gorm:"autoCreateTime"`
+	UpdateTime  time.Time `gorm:"autoUpdateTime"`
+	UserID      uint      `gorm:"not null;index"`
+	SessionID   string    `gorm:"type:char(36);not null;index"`
+	MessageType string    `gorm:"type:varchar(10);not null"`
 	Content     string    `gorm:"type:text"`
+	// ParentID is the message this one follows, 0 for the first message of the session.
+	// Messages sharing a parent are alternative branches of the conversation.
+	ParentID uint `gorm:"not null;default:0;index"`
+	// Model, mode, the token usage and the agent's trace are recorded on answers only
+	Model            string `gorm:"type:varchar(50);not null;default:''"`
+	Mode             string `gorm:"type:varchar(20);not null;default:''"`
+	PromptTokens     int    `gorm:"not null;default:0"`
+	CompletionTokens int    `gorm:"not null;default:0"`
+	// Trace is the JSON array of the steps the agent took to the answer
+	Trace string `gorm:"type:text"`
 }
 
 func (ChatHistory) TableName() string {
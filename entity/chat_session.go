@@ -1,13 +1,31 @@
 package entity
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type ChatSession struct {
-	SessionID   string    `gorm:"primaryKey;type:char(36)"`
-	CreateTime  time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP"`
-	UpdateTime  time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP;update:CURRENT_TIMESTAMP"`
-	UserID      uint      `gorm:"not_null;index"`
-	SessionName string    `gorm:"type:varchar(50)"`
+	SessionID      string    `gorm:"primaryKey;type:char(36)"`
+	CreateTime     time.Time `gorm:"autoCreateTime"`
+	UpdateTime     time.Time `gorm:"autoUpdateTime"`
+	UserID         uint      `gorm:"not null;index"`
+	SessionName    string    `gorm:"type:varchar(50)"`
+	LastActiveTime time.Time `gorm:"autoCreateTime;index"`
+	ArchiveTime    *time.Time
+	DeleteTime     gorm.DeletedAt `gorm:"index"`
+	// MemoryStrategy is one of the agents/memory strategies, empty means the configured default
+	MemoryStrategy string `gorm:"type:varchar(20);not null;default:''"`
+	// MemorySummary is the rolling summary of the summary strategy, it covers messages up to MemorySummaryUntilID
+	MemorySummary        string `gorm:"type:text"`
+	MemorySummaryUntilID uint   `gorm:"not null;default:0"`
+	// AssistantID is the assistant every turn of the session is configured by, 0 for none
+	AssistantID uint `gorm:"not null;default:0;index"`
+	// ActiveMessageID is the last message of the branch that is shown and continued, 0 for an empty session
+	ActiveMessageID uint `gorm:"not null;default:0"`
+	// ChatSettings are inherited by every request of the session that does not set them
+	ChatSettings
 }
 
 func (ChatSession) TableName() string {
@@ -0,0 +1,14 @@
+package entity
+
+// ChatSettings configure how a turn is answered, zero values are inherited
+// from the next level: a request, its session, then the session's assistant
+type ChatSettings struct {
+	Model string `gorm:"type:varchar(50);not null;default:''"`
+	Mode  string `gorm:"type:varchar(20);not null;default:''"`
+	// Temperature and TopP are left to the model when nil, MaxTokens when 0
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int `gorm:"not null;default:0"`
+	// Tools is a comma separated list of the tools the agent may use, empty enables all of them
+	Tools string `gorm:"type:varchar(255);not null;default:''"`
+}
@@ -0,0 +1,32 @@
+package entity
+
+import "time"
+
+// Document is a file uploaded for retrieval, its text is stored as chunks in DocumentChunk
+type Document struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"autoCreateTime"`
+	UpdateTime time.Time `gorm:"autoUpdateTime"`
+	UserID     uint      `gorm:"not null;index"`
+	// SessionID limits the document to one session, it is empty for documents shared by all of the user's sessions
+	SessionID  string `gorm:"type:varchar(36);not null;default:'';index"`
+	FileName   string `gorm:"type:varchar(255);not null"`
+	Size       int64  `gorm:"not null"`
+	ChunkCount int    `gorm:"not null"`
+}
+
+func (Document) TableName() string {
+	return "document"
+}
+
+type DocumentChunk struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"autoCreateTime"`
+	DocumentID uint      `gorm:"not null;index"`
+	ChunkIndex int       `gorm:"not null"`
+	Content    string    `gorm:"type:text"`
+}
+
+func (DocumentChunk) TableName() string {
+	return "document_chunk"
+}
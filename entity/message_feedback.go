@@ -0,0 +1,31 @@
+package entity
+
+import "time"
+
+const (
+	FeedbackRatingUp   = "up"
+	FeedbackRatingDown = "down"
+)
+
+// MessageFeedback is a user's rating of an answer. What produced the answer is copied from it,
+// so reports do not depend on the message, whose trace is found by MessageID.
+type MessageFeedback struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"autoCreateTime;index"`
+	UpdateTime time.Time `gorm:"autoUpdateTime"`
+	UserID     uint      `gorm:"not null;index"`
+	SessionID  string    `gorm:"type:varchar(36);not null;index"`
+	MessageID  uint      `gorm:"not null;uniqueIndex"`
+	// Rating is up or down
+	Rating   string `gorm:"type:varchar(10);not null"`
+	Category string `gorm:"type:varchar(30);not null;default:''"`
+	Comment  string `gorm:"type:varchar(1000);not null;default:''"`
+	Model    string `gorm:"type:varchar(50);not null;default:''"`
+	Mode     string `gorm:"type:varchar(20);not null;default:''"`
+	// Tools are the comma-separated tools the agent called for the answer
+	Tools string `gorm:"type:varchar(255);not null;default:''"`
+}
+
+func (MessageFeedback) TableName() string {
+	return "message_feedback"
+}
@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// ModelComparison is a question answered by several models side by side, the answers are
+// the children of the question, and which of them the user preferred
+type ModelComparison struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"autoCreateTime"`
+	UpdateTime time.Time `gorm:"autoUpdateTime"`
+	UserID     uint      `gorm:"not null;index"`
+	SessionID  string    `gorm:"type:varchar(36);not null;index"`
+	// MessageID is the question the models answered
+	MessageID uint `gorm:"not null;uniqueIndex"`
+	// Models are the comma-separated models that answered
+	Models string `gorm:"type:varchar(255);not null"`
+	// PreferredMessageID is the answer the user preferred, 0 until they pick one
+	PreferredMessageID uint   `gorm:"not null;default:0"`
+	PreferredModel     string `gorm:"type:varchar(50);not null;default:''"`
+}
+
+func (ModelComparison) TableName() string {
+	return "model_comparison"
+}
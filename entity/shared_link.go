@@ -0,0 +1,24 @@
+package entity
+
+import "time"
+
+// SharedLink is a read-only snapshot of a conversation, readable by anyone who has the token
+type SharedLink struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"autoCreateTime"`
+	UserID     uint      `gorm:"not null;index"`
+	SessionID  string    `gorm:"type:varchar(36);not null;index"`
+	Token      string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	Title      string    `gorm:"type:varchar(50);not null;default:''"`
+	// Scope is either the active branch of the session or the whole session with every branch
+	Scope string `gorm:"type:varchar(10);not null"`
+	// Snapshot is the JSON of the shared messages as they were when the link was created,
+	// it has no type so MySQL picks longtext for it
+	Snapshot string
+	// ExpireTime is when the link stops working, nil for never
+	ExpireTime *time.Time
+}
+
+func (SharedLink) TableName() string {
+	return "shared_link"
+}
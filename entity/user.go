@@ -3,13 +3,19 @@ package entity
 import "time"
 
 type User struct {
-	ID         uint      `gorm:"primaryKey;autoIncrement"`
-	CreateTime time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP"`
-	UpDateTime time.Time `gorm:"type:datetime;default:CURRENT_TIMESTAMP;update:CURRENT_TIMESTAMP"`
-	Username   string    `gorm:"type:varchar(50);not_null;unique"`
-	Email      string    `gorm:"type:varchar(100);not_null;unique"`
-	Password   string    `gorm:"type:varchar(100);not_null"`
-	LastLogin  time.Time `gorm:"type:datetime;default:null"`
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime  time.Time `gorm:"autoCreateTime"`
+	UpDateTime  time.Time `gorm:"autoUpdateTime"`
+	Username    string    `gorm:"type:varchar(50);not null;unique"`
+	Email       string    `gorm:"type:varchar(100);not null;unique"`
+	Password    string    `gorm:"type:varchar(100);not null"`
+	DisplayName string    `gorm:"type:varchar(50)"`
+	Preferences string    `gorm:"type:text"`
+	LastLogin   time.Time `gorm:"default:null"`
+	// MemoryEnabled lets facts about the user be remembered across sessions
+	MemoryEnabled bool `gorm:"not null;default:true"`
+	// TokenVersion is raised on a password change, tokens issued with an older one are rejected
+	TokenVersion uint `gorm:"not null;default:0"`
 }
 
 func (User) TableName() string {
@@ -0,0 +1,18 @@
+package entity
+
+import "time"
+
+// UserMemory is a durable fact about a user, remembered across sessions
+type UserMemory struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement"`
+	CreateTime time.Time `gorm:"autoCreateTime"`
+	UpdateTime time.Time `gorm:"autoUpdateTime"`
+	UserID     uint      `gorm:"not null;index"`
+	Content    string    `gorm:"type:varchar(500);not null"`
+	// SessionID is the session the fact was learned in, empty for facts the user added
+	SessionID string `gorm:"type:varchar(36);not null;default:''"`
+}
+
+func (UserMemory) TableName() string {
+	return "user_memory"
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"easy-chat/agents/vectorstores/sqlstore"
+	"easy-chat/blobstore/local"
 	"easy-chat/config"
 	"easy-chat/dao"
 	"easy-chat/router"
+	"easy-chat/service"
 	"easy-chat/service/mq"
+	"easy-chat/validation"
 	"log"
 )
 
@@ -17,14 +21,29 @@ func main() {
 		log.Fatal(err)
 	}
 
+	if err := validation.Init(); err != nil {
+		log.Fatal(err)
+	}
+
 	if err := dao.Init(); err != nil {
 		log.Fatal(err)
 	}
 
+	if config.Get().DataBase.AutoMigrate {
+		if err := dao.MigrateUp(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	service.SetVectorStore(sqlstore.New(dao.DB()))
+	service.SetBlobStore(local.New(service.AttachmentDir()))
+
 	if err := mq.Init(); err != nil {
 		log.Fatal(err)
 	}
 
+	service.StartTrashPurger()
+
 	r := router.SetupRouter()
 	if err := r.Run(":8088"); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"easy-chat/config"
+	"easy-chat/consts"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrNotAdmin = errors.New("admin only")
+
+// AdminMiddleware lets only the configured admins through, it must run after AuthMiddleware
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !slices.Contains(config.Get().Admins, c.GetString(consts.KeyUsername)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": ErrNotAdmin.Error()})
+			return
+		}
+		c.Next()
+	}
+}
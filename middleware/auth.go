@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"context"
 	"easy-chat/config"
+	"easy-chat/consts"
+	"easy-chat/service"
 	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
@@ -19,37 +22,43 @@ var (
 
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if err := authenticateRequest(c); err != nil {
+		username, err := authenticateRequest(c)
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
+		c.Set(consts.KeyUsername, username)
 		c.Next()
 	}
 }
 
-func authenticateRequest(c *gin.Context) error {
+func authenticateRequest(c *gin.Context) (string, error) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
-		return ErrMissedToken
+		return "", ErrMissedToken
 	}
 
 	if !strings.HasPrefix(authHeader, "Bearer ") {
-		return ErrInvalidTokenFormat
+		return "", ErrInvalidTokenFormat
 	}
 
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	return validateToken(tokenString)
+	return validateToken(c.Request.Context(), tokenString)
 }
 
-func validateToken(tokenString string) error {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+func validateToken(ctx context.Context, tokenString string) (string, error) {
+	claims := &service.TokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("%w: %v", ErrUnexpectedSigningMethod, token.Header["alg"])
 		}
 		return []byte(config.Get().SecretKey.JWT), nil
 	})
 	if err != nil || !token.Valid {
-		return ErrInvalidToken
+		return "", ErrInvalidToken
 	}
-	return nil
+	if err := service.CheckTokenClaims(ctx, claims); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims.Issuer, nil
 }
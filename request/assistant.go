@@ -0,0 +1,8 @@
+package request
+
+// AssistantRequest holds every setting of an assistant, updates replace all of them
+type AssistantRequest struct {
+	Name         string `json:"name" binding:"required,max=50"`
+	SystemPrompt string `json:"system_prompt" binding:"max=8000"`
+	ChatSettings
+}
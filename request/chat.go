@@ -1,9 +1,108 @@
 package request
 
+import (
+	"mime/multipart"
+	"time"
+)
+
+// ChatSettings configure how a turn is answered, fields left out are inherited
+type ChatSettings struct {
+	Model       string   `json:"model" binding:"omitempty,model"`
+	Mode        string   `json:"mode" binding:"omitempty,oneof=normal agent rag"`
+	Temperature *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	TopP        *float64 `json:"top_p" binding:"omitempty,gt=0,max=1"`
+	MaxTokens   int      `json:"max_tokens" binding:"omitempty,min=1,max=8192"`
+	// Tools are the tools the agent mode may use, empty enables all of them
+	Tools []string `json:"tools" binding:"omitempty,dive,oneof=web_search knowledge_base"`
+}
+
 type ChatRequest struct {
-	Username  string `json:"username" binding:"required"`
+	// Username is the caller's own, it is never read from the body
+	Username  string `json:"-" binding:"-"`
 	SessionID string `json:"session_id" binding:"required"`
-	Query     string `json:"query" binding:"required"`
-	Model     string `json:"model" binding:"required"`
-	Mode      string `json:"mode"`
+	Query     string `json:"query" binding:"required_unless=Regenerate true,max=8000"`
+	// ParentID is the message the query follows, starting a new branch when it is not the last one.
+	// It defaults to the last message of the session's active branch, 0 starts over from the beginning.
+	ParentID *uint `json:"parent_id"`
+	// Regenerate answers the user message ParentID again instead of asking Query
+	Regenerate bool `json:"regenerate"`
+	// AttachmentIDs are files uploaded to the session that are sent with the query
+	AttachmentIDs []uint `json:"attachment_ids" binding:"omitempty,max=10,dive,min=1"`
+	// CompareModels are the models that answer side by side in the compare mode, it is set by ChatCompareRequest
+	CompareModels []string `json:"compare_models" binding:"-"`
+	// ChatSettings override the session's settings for this turn only
+	ChatSettings
+}
+
+// ChatCompareRequest asks the query of several models at once, their answers stream side by side
+type ChatCompareRequest struct {
+	SessionID     string   `json:"session_id" binding:"required"`
+	Query         string   `json:"query" binding:"required,max=8000"`
+	ParentID      *uint    `json:"parent_id"`
+	AttachmentIDs []uint   `json:"attachment_ids" binding:"omitempty,max=10,dive,min=1"`
+	Models        []string `json:"models" binding:"required,min=2,max=4,unique,dive,model"`
+	Temperature   *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	TopP          *float64 `json:"top_p" binding:"omitempty,gt=0,max=1"`
+	MaxTokens     int      `json:"max_tokens" binding:"omitempty,min=1,max=8192"`
+}
+
+// MessageEditRequest asks an edited version of a user message, the original stays on its own branch
+type MessageEditRequest struct {
+	Query         string `json:"query" binding:"required,max=8000"`
+	AttachmentIDs []uint `json:"attachment_ids" binding:"omitempty,max=10,dive,min=1"`
+	ChatSettings
+}
+
+// AttachmentUploadRequest uploads a file to be sent with a query of the session
+type AttachmentUploadRequest struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+type ChatSessionCreateRequest struct {
+	AssistantID    uint   `json:"assistant_id"`
+	MemoryStrategy string `json:"memory_strategy" binding:"omitempty,oneof=buffer window token_buffer summary vector"`
+	// Settings are inherited by every request of the session
+	Settings ChatSettings `json:"settings"`
+}
+
+// ChatSessionUpdateRequest changes only the fields that are set
+type ChatSessionUpdateRequest struct {
+	SessionName    *string `json:"session_name" binding:"omitempty,max=50"`
+	MemoryStrategy *string `json:"memory_strategy" binding:"omitempty,oneof=buffer window token_buffer summary vector"`
+	// AssistantID binds the session to an assistant, 0 unbinds it
+	AssistantID *uint `json:"assistant_id"`
+	// Settings replace all of the session's settings
+	Settings *ChatSettings `json:"settings"`
+}
+
+type ChatExportRequest struct {
+	Format string `form:"format" binding:"omitempty,oneof=md json html"`
+	// Trace adds the steps the agent took to its answers
+	Trace bool `form:"trace"`
+}
+
+// ChatImportRequest uploads a JSON export or a ChatGPT export to recreate sessions from
+type ChatImportRequest struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+}
+
+type SharedLinkCreateRequest struct {
+	// Scope shares the active branch by default, or every branch of the session
+	Scope string `json:"scope" binding:"omitempty,oneof=branch session"`
+	// ExpiresInHours makes the link stop working after that long, 0 keeps it working until it is revoked
+	ExpiresInHours int `json:"expires_in_hours" binding:"omitempty,min=1,max=8760"`
+}
+
+// MessageFeedbackRequest rates an answer, rating it again replaces the earlier rating
+type MessageFeedbackRequest struct {
+	Rating   string `json:"rating" binding:"required,oneof=up down"`
+	Category string `json:"category" binding:"omitempty,oneof=inaccurate unhelpful harmful off_topic too_long other"`
+	Comment  string `json:"comment" binding:"omitempty,max=1000"`
+}
+
+// FeedbackReportRequest selects the days of a feedback report, the last 30 days by default
+type FeedbackReportRequest struct {
+	From     time.Time `form:"from" time_format:"2006-01-02"`
+	To       time.Time `form:"to" time_format:"2006-01-02"`
+	Interval string    `form:"interval" binding:"omitempty,oneof=day week"`
 }
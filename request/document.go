@@ -0,0 +1,13 @@
+package request
+
+import "mime/multipart"
+
+type DocumentUploadRequest struct {
+	File *multipart.FileHeader `form:"file" binding:"required"`
+	// SessionID limits the document to one session, without it the document is shared by all sessions
+	SessionID string `form:"session_id"`
+}
+
+type DocumentListRequest struct {
+	SessionID string `form:"session_id"`
+}
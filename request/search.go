@@ -0,0 +1,18 @@
+package request
+
+import "time"
+
+type SearchRequest struct {
+	Query     string    `form:"q" binding:"required,max=200"`
+	SessionID string    `form:"session_id"`
+	Role      string    `form:"role" binding:"omitempty,oneof=user ai"`
+	From      time.Time `form:"from" time_format:"2006-01-02"`
+	To        time.Time `form:"to" time_format:"2006-01-02"`
+	Limit     int       `form:"limit" binding:"omitempty,min=1,max=200"`
+}
+
+type SemanticSearchRequest struct {
+	Query     string `form:"q" binding:"required,max=2000"`
+	SessionID string `form:"session_id"`
+	Limit     int    `form:"limit" binding:"omitempty,min=1,max=50"`
+}
@@ -1,12 +1,31 @@
 package request
 
+import "encoding/json"
+
 type UserRegisterRequest struct {
-	Username string `json:"username" binding:"required"`
-	Email    string `json:"email" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username string `json:"username" binding:"required,min=3,max=50,username"`
+	Email    string `json:"email" binding:"required,max=100,email"`
+	Password string `json:"password" binding:"required,password"`
 }
 
 type UserLoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 }
+
+type UserUpdateRequest struct {
+	Email       *string         `json:"email" binding:"omitempty,max=100,email"`
+	DisplayName *string         `json:"display_name" binding:"omitempty,max=50"`
+	Preferences json.RawMessage `json:"preferences"`
+	// MemoryEnabled turns remembering facts across sessions on or off
+	MemoryEnabled *bool `json:"memory_enabled"`
+}
+
+type UserMemoryRequest struct {
+	Content string `json:"content" binding:"required,max=500"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,password"`
+}
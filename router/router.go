@@ -14,14 +14,61 @@ func SetupRouter() *gin.Engine {
 
 	r.POST("/api/login", controller.UserLoginAPI)
 	r.POST("/api/register", controller.UserRegisterAPI)
+	// shared links are read by anyone who has the token
+	r.GET("/api/shared/:token", controller.GetSharedConversationAPI)
 
 	r.Use(middleware.AuthMiddleware())
 
+	r.GET("/api/me", controller.GetUserProfileAPI)
+	r.PATCH("/api/me", controller.UpdateUserProfileAPI)
+	r.POST("/api/me/password", controller.ChangePasswordAPI)
+	r.DELETE("/api/me", controller.DeleteUserAPI)
+	r.GET("/api/me/memories", controller.GetUserMemoriesAPI)
+	r.POST("/api/me/memories", controller.AddUserMemoryAPI)
+	r.DELETE("/api/me/memories", controller.ClearUserMemoriesAPI)
+	r.PATCH("/api/me/memories/:memory_id", controller.UpdateUserMemoryAPI)
+	r.DELETE("/api/me/memories/:memory_id", controller.DeleteUserMemoryAPI)
+
+	r.GET("/api/chat-sessions", controller.GetChatSessionsAPI)
+	// deprecated listing by username, gin needs the wildcard named like the one of the export below
+	r.GET("/api/chat-session/:session_id", controller.GetUserChatSessionsAPI)
 	r.POST("/api/chat-session", controller.CreateChatSessionAPI)
-	r.GET("/api/chat-session/:username", controller.GetUserChatSessionAPI)
+	r.POST("/api/chat-session/import", controller.ImportChatSessionAPI)
+	r.PATCH("/api/chat-session/:session_id", controller.UpdateChatSessionAPI)
 	r.DELETE("/api/chat-session/:session_id", controller.DeleteChatSessionAPI)
+	r.POST("/api/chat-session/:session_id/archive", controller.ArchiveChatSessionAPI)
+	r.POST("/api/chat-session/:session_id/unarchive", controller.UnarchiveChatSessionAPI)
+	r.POST("/api/chat-session/:session_id/restore", controller.RestoreChatSessionAPI)
+	r.GET("/api/chat-session/:session_id/export", controller.ExportChatSessionAPI)
+	r.POST("/api/chat-session/:session_id/share", controller.CreateSharedLinkAPI)
+	r.POST("/api/chat-session/:session_id/attachments", controller.UploadAttachmentAPI)
+	r.GET("/api/attachments/:attachment_id/content", controller.GetAttachmentContentAPI)
+	r.DELETE("/api/attachments/:attachment_id", controller.DeleteAttachmentAPI)
+	r.GET("/api/shared-links", controller.GetSharedLinksAPI)
+	r.DELETE("/api/shared-links/:link_id", controller.RevokeSharedLinkAPI)
+	r.POST("/api/chat-session/:session_id/messages/:message_id/regenerate", controller.RegenerateMessageAPI)
+	r.POST("/api/chat-session/:session_id/messages/:message_id/edit", controller.EditMessageAPI)
+	r.POST("/api/chat-session/:session_id/messages/:message_id/activate", controller.ActivateMessageAPI)
+	r.PUT("/api/chat-session/:session_id/messages/:message_id/feedback", controller.RateMessageAPI)
+	r.DELETE("/api/chat-session/:session_id/messages/:message_id/feedback", controller.DeleteMessageFeedbackAPI)
+	r.POST("/api/chat-session/:session_id/messages/:message_id/prefer", controller.PreferAnswerAPI)
 	r.GET("/api/chat-history/:session_id", controller.GetChatHistoryAPI)
 	r.POST("/api/chat", controller.ChatAPI)
+	r.POST("/api/chat/compare", controller.CompareChatAPI)
+	r.GET("/api/search", controller.SearchAPI)
+	r.GET("/api/search/semantic", controller.SemanticSearchAPI)
+	r.POST("/api/documents", controller.UploadDocumentAPI)
+	r.GET("/api/documents", controller.GetDocumentsAPI)
+	r.DELETE("/api/documents/:document_id", controller.DeleteDocumentAPI)
+	r.POST("/api/assistants", controller.CreateAssistantAPI)
+	r.GET("/api/assistants", controller.GetAssistantsAPI)
+	r.GET("/api/assistants/:assistant_id", controller.GetAssistantAPI)
+	r.PUT("/api/assistants/:assistant_id", controller.UpdateAssistantAPI)
+	r.DELETE("/api/assistants/:assistant_id", controller.DeleteAssistantAPI)
+
+	admin := r.Group("/api/admin", middleware.AdminMiddleware())
+	admin.GET("/feedback-report", controller.GetFeedbackReportAPI)
+	admin.GET("/comparison-report", controller.GetComparisonReportAPI)
 
 	return r
 }
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"fmt"
+)
+
+var ErrAssistantNotFound = errors.New("assistant not found")
+
+// the tools an assistant can enable for the agent mode
+const (
+	ToolWebSearch     = "web_search"
+	ToolKnowledgeBase = "knowledge_base"
+)
+
+func CreateAssistant(ctx context.Context, username string, request *request.AssistantRequest) (*entity.Assistant, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	assistant := &entity.Assistant{UserID: user.ID}
+	applyAssistantRequest(assistant, request)
+
+	if err := repositories.Assistants.Create(assistant); err != nil {
+		return nil, err
+	}
+	return assistant, nil
+}
+
+func ListAssistants(ctx context.Context, username string) ([]*entity.Assistant, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	return repositories.Assistants.GetByUserID(user.ID)
+}
+
+func GetAssistant(ctx context.Context, username string, assistantID uint) (*entity.Assistant, error) {
+	return getOwnedAssistant(username, assistantID)
+}
+
+// UpdateAssistant replaces the assistant's settings, the sessions bound to it pick them up on their next turn
+func UpdateAssistant(ctx context.Context, username string, assistantID uint, request *request.AssistantRequest) (*entity.Assistant, error) {
+	assistant, err := getOwnedAssistant(username, assistantID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyAssistantRequest(assistant, request)
+	if err := repositories.Assistants.Update(assistant); err != nil {
+		return nil, err
+	}
+	return assistant, nil
+}
+
+// DeleteAssistant removes the assistant and unbinds the sessions that used it
+func DeleteAssistant(ctx context.Context, username string, assistantID uint) error {
+	if _, err := getOwnedAssistant(username, assistantID); err != nil {
+		return err
+	}
+	return repositories.Assistants.Delete(assistantID)
+}
+
+// SetChatSessionAssistant binds the session to one of the user's assistants, 0 unbinds it
+func SetChatSessionAssistant(ctx context.Context, username, sessionID string, assistantID uint) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+
+	if assistantID != 0 {
+		if _, err := getOwnedAssistant(username, assistantID); err != nil {
+			return err
+		}
+	}
+
+	return repositories.Sessions.SetAssistant(sessionID, assistantID)
+}
+
+func getOwnedAssistant(username string, assistantID uint) (*entity.Assistant, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	assistant, err := repositories.Assistants.GetByID(assistantID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAssistantNotFound, err)
+	}
+	if assistant.UserID != user.ID {
+		return nil, fmt.Errorf("%w: %d", ErrAssistantNotFound, assistantID)
+	}
+
+	return assistant, nil
+}
+
+func applyAssistantRequest(assistant *entity.Assistant, request *request.AssistantRequest) {
+	assistant.Name = request.Name
+	assistant.SystemPrompt = request.SystemPrompt
+	assistant.ChatSettings = toChatSettings(&request.ChatSettings)
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"easy-chat/agents/documentloaders"
+	"easy-chat/agents/llms"
+	"easy-chat/agents/llms/qwen"
+	"easy-chat/config"
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAttachmentNotFound     = errors.New("attachment not found")
+	ErrAttachmentTooLarge     = errors.New("attachment too large")
+	ErrUnsupportedAttachment  = errors.New("unsupported attachment type")
+	ErrAttachmentAlreadySent  = errors.New("attachment already sent")
+	ErrModelWithoutImages     = errors.New("model does not accept images")
+	ErrBlobStoreNotConfigured = errors.New("blob store not configured")
+)
+
+const (
+	defaultMaxAttachmentSize    = 10
+	defaultAttachmentDir        = "data/attachments"
+	defaultMaxAttachmentTokens  = 8000
+	defaultUnsentRetentionHours = 24
+)
+
+// imageTypes are the sniffed content types that are sent to the model as images
+var imageTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// MaxAttachmentSize returns the largest accepted attachment in bytes
+func MaxAttachmentSize() int64 {
+	size := config.Get().Attachment.MaxUploadSize
+	if size <= 0 {
+		size = defaultMaxAttachmentSize
+	}
+	return int64(size) << 20
+}
+
+// maxAttachmentTokens returns how many tokens of an attached file's text go into the prompt
+func maxAttachmentTokens() int {
+	if tokens := config.Get().Attachment.MaxTextTokens; tokens > 0 {
+		return tokens
+	}
+	return defaultMaxAttachmentTokens
+}
+
+// AttachmentDir returns where the local blob store keeps attachments
+func AttachmentDir() string {
+	if dir := config.Get().Attachment.Dir; dir != "" {
+		return dir
+	}
+	return defaultAttachmentDir
+}
+
+// UploadAttachment keeps the file for a later query of the session. Images are told apart by their content,
+// any other file must be one whose text can be extracted, as for documents.
+func UploadAttachment(ctx context.Context, username, sessionID, fileName string, content []byte) (*entity.Attachment, error) {
+	if blobStore == nil {
+		return nil, ErrBlobStoreNotConfigured
+	}
+
+	if int64(len(content)) > MaxAttachmentSize() {
+		return nil, fmt.Errorf("%w: %d bytes", ErrAttachmentTooLarge, len(content))
+	}
+
+	session, err := getOwnedChatSession(username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, contentType, err := detectAttachmentType(fileName, content)
+	if err != nil {
+		return nil, err
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return nil, err
+	}
+
+	attachment := &entity.Attachment{
+		UserID:      session.UserID,
+		SessionID:   sessionID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Kind:        kind,
+		Size:        int64(len(content)),
+		// the session is the directory, so deleting the session deletes its files at once
+		BlobKey: sessionID + "/" + hex.EncodeToString(name) + strings.ToLower(filepath.Ext(fileName)),
+	}
+
+	if err := blobStore.Put(ctx, attachment.BlobKey, content); err != nil {
+		return nil, err
+	}
+
+	if err := repositories.Attachments.Create(attachment); err != nil {
+		deleteBlob(ctx, attachment.BlobKey)
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// GetAttachmentContent returns the attachment with its file, to the user who uploaded it
+func GetAttachmentContent(ctx context.Context, username string, attachmentID uint) (*entity.Attachment, []byte, error) {
+	attachment, err := getOwnedAttachment(username, attachmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if blobStore == nil {
+		return nil, nil, ErrBlobStoreNotConfigured
+	}
+
+	content, err := blobStore.Get(ctx, attachment.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// DeleteAttachment removes a file that has not been sent yet, sent files stay with their message
+func DeleteAttachment(ctx context.Context, username string, attachmentID uint) error {
+	attachment, err := getOwnedAttachment(username, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	if attachment.MessageID != 0 {
+		return fmt.Errorf("%w: %d", ErrAttachmentAlreadySent, attachmentID)
+	}
+
+	if err := repositories.Attachments.Delete(attachmentID); err != nil {
+		return err
+	}
+	deleteBlob(ctx, attachment.BlobKey)
+	return nil
+}
+
+// getMessageAttachments returns the files sent with each of the messages
+func getMessageAttachments(ctx context.Context, messageIDs []uint) (map[uint][]*entity.Attachment, error) {
+	attachments, err := repositories.Attachments.GetByMessageIDs(messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byMessage := make(map[uint][]*entity.Attachment)
+	for _, attachment := range attachments {
+		byMessage[attachment.MessageID] = append(byMessage[attachment.MessageID], attachment)
+	}
+	return byMessage, nil
+}
+
+func getOwnedAttachment(username string, attachmentID uint) (*entity.Attachment, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := repositories.Attachments.GetByIDs([]uint{attachmentID})
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 || attachments[0].UserID != user.ID {
+		return nil, fmt.Errorf("%w: %d", ErrAttachmentNotFound, attachmentID)
+	}
+	return attachments[0], nil
+}
+
+// detectAttachmentType sniffs the content instead of trusting the name or the type the client sent
+func detectAttachmentType(fileName string, content []byte) (string, string, error) {
+	contentType := http.DetectContentType(content)
+	if slices.Contains(imageTypes, contentType) {
+		return entity.AttachmentKindImage, contentType, nil
+	}
+
+	if contentType != "application/pdf" && !strings.HasPrefix(contentType, "text/") {
+		return "", "", fmt.Errorf("%w: %s", ErrUnsupportedAttachment, contentType)
+	}
+	if _, err := documentloaders.LoadFile(fileName, content); err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrUnsupportedAttachment, err)
+	}
+	return entity.AttachmentKindText, contentType, nil
+}
+
+// purgeUnsentAttachments deletes the uploads before the time that were never sent with a query,
+// otherwise they would only go away with their session
+func purgeUnsentAttachments(ctx context.Context, before time.Time) {
+	attachments, err := repositories.Attachments.GetUnsentBefore(before)
+	if err != nil {
+		log.Printf("failed to purge unsent attachments: %v", err)
+		return
+	}
+
+	for _, attachment := range attachments {
+		if err := repositories.Attachments.Delete(attachment.ID); err != nil {
+			log.Printf("failed to delete attachment %d: %v", attachment.ID, err)
+			continue
+		}
+		deleteBlob(ctx, attachment.BlobKey)
+	}
+	if len(attachments) > 0 {
+		log.Printf("purged %d unsent attachments", len(attachments))
+	}
+}
+
+func unsentAttachmentExpiryTime() time.Time {
+	hours := config.Get().Attachment.UnsentRetentionHours
+	if hours <= 0 {
+		hours = defaultUnsentRetentionHours
+	}
+	return time.Now().Add(-time.Duration(hours) * time.Hour)
+}
+
+// deleteBlob removes the file of a deleted attachment, a failure only leaves an unused file behind
+func deleteBlob(ctx context.Context, key string) {
+	if blobStore == nil {
+		return
+	}
+	if err := blobStore.Delete(ctx, key); err != nil {
+		log.Printf("failed to delete blob %s: %v", key, err)
+	}
+}
+
+// deleteSessionBlobs removes the files of every attachment of the sessions
+func deleteSessionBlobs(ctx context.Context, sessionIDs ...string) {
+	if blobStore == nil {
+		return
+	}
+	for _, sessionID := range sessionIDs {
+		if err := blobStore.DeleteDir(ctx, sessionID); err != nil {
+			log.Printf("failed to delete the attachments of session %s: %v", sessionID, err)
+		}
+	}
+}
+
+// chatAttachments are the files sent with a query, read for its prompt
+type chatAttachments struct {
+	attachments []*entity.Attachment
+	// files are the texts of the text files, each under its file name
+	files  []string
+	images []llms.Image
+	// truncated are the names of the files whose text was cut to maxAttachmentTokens
+	truncated []string
+}
+
+// loadChatAttachments reads the files the request sends, a regenerated answer gets the files of its question.
+// The text of a file is cut to maxAttachmentTokens, which is marked in the text and sent as an event.
+func loadChatAttachments(ctx context.Context, request *request.ChatRequest) (*chatAttachments, error) {
+	attachments, err := getRequestAttachments(request)
+	if err != nil {
+		return nil, err
+	}
+	if len(attachments) == 0 {
+		return &chatAttachments{}, nil
+	}
+
+	if blobStore == nil {
+		return nil, ErrBlobStoreNotConfigured
+	}
+
+	loaded := &chatAttachments{attachments: attachments}
+	maxTokens := maxAttachmentTokens()
+	for _, attachment := range attachments {
+		content, err := blobStore.Get(ctx, attachment.BlobKey)
+		if err != nil {
+			return nil, err
+		}
+
+		if attachment.Kind == entity.AttachmentKindImage {
+			loaded.images = append(loaded.images, llms.Image{MIMEType: attachment.ContentType, Data: content})
+			continue
+		}
+
+		text, err := documentloaders.LoadFile(attachment.FileName, content)
+		if err != nil {
+			return nil, err
+		}
+		if truncated := newTokenizer(request.Model).Truncate(text, maxTokens); len(truncated) < len(text) {
+			text = fmt.Sprintf("%s\n[truncated, only the first %d tokens of the file are shown]", truncated, maxTokens)
+			loaded.truncated = append(loaded.truncated, attachment.FileName)
+		}
+		loaded.files = append(loaded.files, "--- "+attachment.FileName+" ---\n"+text+"\n")
+	}
+
+	if len(loaded.images) > 0 {
+		models := []string{request.Model}
+		if request.Mode == ModeCompare {
+			models = request.CompareModels
+		}
+		for _, model := range models {
+			if !qwen.SupportsImages(model) {
+				return nil, fmt.Errorf("%w: %s", ErrModelWithoutImages, model)
+			}
+		}
+	}
+
+	if len(loaded.truncated) > 0 {
+		if eventFunc, exists := ctx.Value(consts.KeyEventFunc).(consts.EventFunc); exists {
+			eventFunc(consts.SSEventAttachmentsTruncated, loaded.truncated)
+		}
+	}
+	return loaded, nil
+}
+
+func getRequestAttachments(request *request.ChatRequest) ([]*entity.Attachment, error) {
+	if request.Regenerate {
+		return repositories.Attachments.GetByMessageIDs([]uint{*request.ParentID})
+	}
+	if len(request.AttachmentIDs) == 0 {
+		return nil, nil
+	}
+
+	user, err := repositories.Users.GetByUsername(request.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	attachments, err := repositories.Attachments.GetByIDs(request.AttachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range request.AttachmentIDs {
+		i := slices.IndexFunc(attachments, func(attachment *entity.Attachment) bool {
+			return attachment.ID == id
+		})
+		if i < 0 || attachments[i].UserID != user.ID || attachments[i].SessionID != request.SessionID {
+			return nil, fmt.Errorf("%w: %d", ErrAttachmentNotFound, id)
+		}
+		if attachments[i].MessageID != 0 {
+			return nil, fmt.Errorf("%w: %d", ErrAttachmentAlreadySent, id)
+		}
+	}
+	return attachments, nil
+}
+
+// query puts the text of the files below the query
+func (a *chatAttachments) query(query string) string {
+	if len(a.files) == 0 {
+		return query
+	}
+	return query + "\n\nAttached Files:\n" + strings.Join(a.files, "\n")
+}
+
+func (a *chatAttachments) callOptions() []llms.CallOption {
+	if len(a.images) == 0 {
+		return nil
+	}
+	return []llms.CallOption{llms.WithImages(a.images...)}
+}
+
+// attachTo links newly sent files to the saved user message, which shows them in the history
+func (a *chatAttachments) attachTo(messageID uint) error {
+	var ids []uint
+	for _, attachment := range a.attachments {
+		if attachment.MessageID == 0 {
+			ids = append(ids, attachment.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return repositories.Attachments.AttachToMessage(ids, messageID)
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"easy-chat/blobstore"
+	"easy-chat/blobstore/local"
+	"easy-chat/config"
+	"easy-chat/consts"
+	"easy-chat/request"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadChatAttachmentsTruncatesText(t *testing.T) {
+	useInMemoryRepositories(t)
+	previous := blobStore
+	SetBlobStore(local.New(t.TempDir()))
+	t.Cleanup(func() { SetBlobStore(previous) })
+
+	maxTokens := config.Get().Attachment.MaxTextTokens
+	config.Get().Attachment.MaxTextTokens = 100
+	t.Cleanup(func() { config.Get().Attachment.MaxTextTokens = maxTokens })
+
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+	var truncated []string
+	ctx := context.WithValue(context.Background(), consts.KeyEventFunc, consts.EventFunc(func(event string, data interface{}) {
+		if event == consts.SSEventAttachmentsTruncated {
+			truncated = data.([]string)
+		}
+	}))
+
+	short, err := UploadAttachment(ctx, "alice", sessionID, "short.txt", []byte("a short note"))
+	if err != nil {
+		t.Fatalf("UploadAttachment(short) error = %v", err)
+	}
+	long, err := UploadAttachment(ctx, "alice", sessionID, "long.txt", []byte(strings.Repeat("word ", 1000)))
+	if err != nil {
+		t.Fatalf("UploadAttachment(long) error = %v", err)
+	}
+
+	attachments, err := loadChatAttachments(ctx, &request.ChatRequest{
+		Username:      "alice",
+		SessionID:     sessionID,
+		ChatSettings:  request.ChatSettings{Model: "qwen-plus"},
+		AttachmentIDs: []uint{short.ID, long.ID},
+	})
+	if err != nil {
+		t.Fatalf("loadChatAttachments() error = %v", err)
+	}
+
+	if !slices.Equal(truncated, []string{"long.txt"}) {
+		t.Errorf("truncated files = %v, want [long.txt]", truncated)
+	}
+	if !strings.Contains(attachments.files[0], "a short note") || strings.Contains(attachments.files[0], "truncated") {
+		t.Errorf("short file = %q, want its whole text", attachments.files[0])
+	}
+	if tokens := newTokenizer("qwen-plus").CountTokens(attachments.files[1]); tokens > 150 || !strings.Contains(attachments.files[1], "[truncated") {
+		t.Errorf("long file has %d tokens, want it cut to about 100 and marked", tokens)
+	}
+}
+
+func TestPurgeUnsentAttachments(t *testing.T) {
+	useInMemoryRepositories(t)
+	previous := blobStore
+	SetBlobStore(local.New(t.TempDir()))
+	t.Cleanup(func() { SetBlobStore(previous) })
+
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+	ctx := context.Background()
+
+	unsent, err := UploadAttachment(ctx, "alice", sessionID, "unsent.txt", []byte("never sent"))
+	if err != nil {
+		t.Fatalf("UploadAttachment(unsent) error = %v", err)
+	}
+	sent, err := UploadAttachment(ctx, "alice", sessionID, "sent.txt", []byte("sent with a query"))
+	if err != nil {
+		t.Fatalf("UploadAttachment(sent) error = %v", err)
+	}
+	question := saveTestExchange(t, "alice", sessionID, nil, "what is in the file")
+	if err := repositories.Attachments.AttachToMessage([]uint{sent.ID}, question[0].ID); err != nil {
+		t.Fatalf("AttachToMessage() error = %v", err)
+	}
+
+	purgeUnsentAttachments(ctx, time.Now().Add(-time.Hour))
+	if _, _, err := GetAttachmentContent(ctx, "alice", unsent.ID); err != nil {
+		t.Fatalf("GetAttachmentContent() of a recent upload error = %v, want it kept", err)
+	}
+
+	purgeUnsentAttachments(ctx, time.Now().Add(time.Minute))
+	if _, _, err := GetAttachmentContent(ctx, "alice", unsent.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Errorf("GetAttachmentContent() of the unsent upload error = %v, want %v", err, ErrAttachmentNotFound)
+	}
+	if _, err := blobStore.Get(ctx, unsent.BlobKey); !errors.Is(err, blobstore.ErrBlobNotFound) {
+		t.Errorf("blobStore.Get() of the unsent upload error = %v, want %v", err, blobstore.ErrBlobNotFound)
+	}
+	if _, _, err := GetAttachmentContent(ctx, "alice", sent.ID); err != nil {
+		t.Errorf("GetAttachmentContent() of the sent file error = %v, want it kept", err)
+	}
+}
@@ -3,39 +3,72 @@ package service
 import (
 	"context"
 	"easy-chat/agents"
+	"easy-chat/agents/contextwindow"
 	"easy-chat/agents/llms"
 	"easy-chat/agents/llms/qwen"
 	"easy-chat/agents/memory"
+	"easy-chat/agents/prompts"
 	"easy-chat/agents/toolkit"
 	"easy-chat/agents/toolkit/exa"
 	"easy-chat/config"
 	"easy-chat/consts"
-	"easy-chat/dao"
+	"easy-chat/entity"
 	"easy-chat/request"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 )
 
 const (
 	ModeNormal = "normal"
 	ModeAgent  = "agent"
+	ModeRAG    = "rag"
+	// ModeCompare asks several models the same query, it is only started through the compare API
+	ModeCompare = "compare"
 )
 
 var ErrInvalidMode = errors.New("invalid mode")
 
 func HandleChat(ctx context.Context, request *request.ChatRequest) error {
-	var result string
-	var err error
+	session, err := getOwnedChatSession(request.Username, request.SessionID)
+	if err != nil {
+		return err
+	}
+	if session.DeleteTime.Valid {
+		return fmt.Errorf("%w: %s is in the trash", ErrSessionNotFound, request.SessionID)
+	}
 
+	settings, err := resolveChatSettings(request, session)
+	if err != nil {
+		return err
+	}
+	if err := prepareBranch(request); err != nil {
+		return err
+	}
+	attachments, err := loadChatAttachments(ctx, request)
+	if err != nil {
+		return err
+	}
+	if request.Mode == ModeCompare {
+		return handleCompareChat(ctx, request, settings, attachments)
+	}
+
+	var answer *chatAnswer
 	switch request.Mode {
 	case ModeNormal:
-		result, err = handleNormalChat(ctx, request)
+		answer, err = handleNormalChat(ctx, request, settings, attachments)
 		if err != nil {
 			return err
 		}
 	case ModeAgent:
-		result, err = handleAgentChat(ctx, request)
+		answer, err = handleAgentChat(ctx, request, settings, attachments)
+		if err != nil {
+			return err
+		}
+	case ModeRAG:
+		answer, err = handleRAGChat(ctx, request, settings, attachments)
 		if err != nil {
 			return err
 		}
@@ -43,89 +76,306 @@ func HandleChat(ctx context.Context, request *request.ChatRequest) error {
 		return fmt.Errorf("%w: %s", ErrInvalidMode, request.Mode)
 	}
 
-	if err := dao.SaveChatHistory(request, []memory.Message{
-		{Role: memory.MessageRoleUser, Content: request.Query},
-		{Role: memory.MessageRoleAI, Content: result},
-	}); err != nil {
+	reply, err := answer.toChatHistory(request.Model, request.Mode)
+	if err != nil {
 		return err
 	}
 
+	// a regenerated answer is saved below the user message it answers, which is already saved
+	chatHistories := []*entity.ChatHistory{{MessageType: memory.MessageRoleUser, Content: request.Query}, reply}
+	if request.Regenerate {
+		chatHistories = chatHistories[1:]
+	}
+
+	chatHistories, err = repositories.Histories.Save(request, chatHistories)
+	if err != nil {
+		return err
+	}
+	if !request.Regenerate {
+		if err := attachments.attachTo(chatHistories[0].ID); err != nil {
+			return err
+		}
+	}
+
+	go embedChatHistories(chatHistories)
+	if !request.Regenerate {
+		go extractUserMemories(request.Username, request.SessionID, request.Query, answer.Content)
+	}
+	titles := startSessionTitle(request.SessionID, request.Query, answer.Content)
+	sendSessionTitle(ctx, request.SessionID, titles)
+
 	return nil
 }
 
-func handleNormalChat(ctx context.Context, request *request.ChatRequest) (string, error) {
+// chatAnswer is what a mode answered and what it cost, only the agent leaves a trace
+type chatAnswer struct {
+	Content string
+	Usage   llms.Usage
+	Trace   []agents.Step
+}
+
+// addUsage adds up the usage of every call to the LLM, the agent makes one per step
+func (a *chatAnswer) addUsage(usage llms.Usage) {
+	a.Usage.PromptTokens += usage.PromptTokens
+	a.Usage.CompletionTokens += usage.CompletionTokens
+}
+
+func (a *chatAnswer) addStep(step agents.Step) {
+	a.Trace = append(a.Trace, step)
+}
+
+func (a *chatAnswer) toChatHistory(model, mode string) (*entity.ChatHistory, error) {
+	chatHistory := &entity.ChatHistory{
+		MessageType:      memory.MessageRoleAI,
+		Content:          a.Content,
+		Model:            model,
+		Mode:             mode,
+		PromptTokens:     a.Usage.PromptTokens,
+		CompletionTokens: a.Usage.CompletionTokens,
+	}
+
+	if len(a.Trace) > 0 {
+		trace, err := json.Marshal(a.Trace)
+		if err != nil {
+			return nil, err
+		}
+		chatHistory.Trace = string(trace)
+	}
+	return chatHistory, nil
+}
+
+func handleNormalChat(ctx context.Context, request *request.ChatRequest, settings *chatSettings, attachments *chatAttachments) (*chatAnswer, error) {
 	cfg := config.Get()
 	llm, err := qwen.New(
 		qwen.WithModelName(request.Model),
 		qwen.WithAPIKey(cfg.APIKey.Qwen),
 	)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	prompt, err := buildPrompt(request)
+	assembler := settings.newAssembler(request.Model)
+	prompt, err := buildPrompt(ctx, request, settings, assembler, attachments.query(request.Query))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	streamFunc, exists := ctx.Value(consts.KeyStreamFunc).(llms.StreamFunc)
 	if !exists {
-		return "", fmt.Errorf("%w: %s", consts.ErrInvalidContextKey, consts.KeyStreamFunc)
+		return nil, fmt.Errorf("%w: %s", consts.ErrInvalidContextKey, consts.KeyStreamFunc)
 	}
 
-	result, err := llm.GenerateContent(ctx, prompt, llms.WithStreamFunc(streamFunc))
+	answer := &chatAnswer{}
+	callOptions := append(settings.callOptions(streamFunc, assembler), llms.WithUsageFunc(answer.addUsage))
+	callOptions = append(callOptions, attachments.callOptions()...)
+	if answer.Content, err = llm.GenerateContent(ctx, prompt, callOptions...); err != nil {
+		return nil, err
+	}
+
+	return answer, nil
+}
+
+func handleAgentChat(ctx context.Context, request *request.ChatRequest, settings *chatSettings, attachments *chatAttachments) (*chatAnswer, error) {
+	cfg := config.Get()
+	llm, err := qwen.New(
+		qwen.WithModelName(request.Model),
+		qwen.WithAPIKey(cfg.APIKey.Qwen),
+	)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return result, nil
+	tools, err := newAgentTools(ctx, request, SplitTools(settings.Tools))
+	if err != nil {
+		return nil, err
+	}
+
+	mem, err := newSessionMemory(request, settings.session)
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &chatAnswer{}
+	callOptions := append(settings.generationOptions(), llms.WithUsageFunc(answer.addUsage))
+	callOptions = append(callOptions, attachments.callOptions()...)
+	agent, err := agents.NewAgent(llm, tools,
+		agents.WithMemory(mem),
+		agents.WithAssembler(settings.newAssembler(request.Model)),
+		agents.WithUserMemories(recallUserMemories(ctx, request.Username, request.Query)),
+		agents.WithSystemPrompt(settings.SystemPrompt),
+		agents.WithCallOptions(callOptions...),
+		agents.WithStepFunc(answer.addStep),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// the agent reads the files as part of the question
+	agentRequest := *request
+	agentRequest.Query = attachments.query(request.Query)
+	if answer.Content, err = agent.Execute(ctx, &agentRequest); err != nil {
+		return nil, err
+	}
+
+	return answer, nil
 }
 
-func handleAgentChat(ctx context.Context, request *request.ChatRequest) (string, error) {
+// handleRAGChat answers from the user's documents, the excerpts it used are sent
+// as a citations event before the answer starts streaming
+func handleRAGChat(ctx context.Context, request *request.ChatRequest, settings *chatSettings, attachments *chatAttachments) (*chatAnswer, error) {
 	cfg := config.Get()
 	llm, err := qwen.New(
 		qwen.WithModelName(request.Model),
 		qwen.WithAPIKey(cfg.APIKey.Qwen),
 	)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	searchTool, err := exa.NewSearchTool(cfg.APIKey.Exa)
+	streamFunc, exists := ctx.Value(consts.KeyStreamFunc).(llms.StreamFunc)
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", consts.ErrInvalidContextKey, consts.KeyStreamFunc)
+	}
+
+	citations, err := retrieveCitations(ctx, request.Username, request.SessionID, request.Query)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	tools := []toolkit.Tool{searchTool}
+	history, err := loadHistoryPart(ctx, request, settings.session)
+	if err != nil {
+		return nil, err
+	}
 
-	agent, err := agents.NewAgent(llm, tools)
+	// the history goes first when the prompt is too long, then the least relevant excerpts, then the facts about the user
+	documents := &contextwindow.Part{Items: formatCitations(citations), Priority: 1}
+	facts := loadUserMemoryPart(ctx, request)
+
+	assembler := settings.newAssembler(request.Model)
+	fixed, err := prompts.Render(prompts.RAGPromptTemplate, map[string]interface{}{
+		"documents":    "",
+		"conversation": formatConversation(nil, nil, attachments.query(request.Query)),
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	if err := assembler.Fit(settings.SystemPrompt+fixed, history, documents, facts); err != nil {
+		return nil, err
+	}
+	citations = citations[:len(documents.Items)]
+
+	if eventFunc, exists := ctx.Value(consts.KeyEventFunc).(consts.EventFunc); exists {
+		eventFunc(consts.SSEventCitations, citations)
+	}
+
+	documentText := strings.Join(documents.Items, "\n\n")
+	if documentText == "" {
+		documentText = "(no relevant excerpts found)"
 	}
 
-	result, err := agent.Execute(ctx, request)
+	prompt, err := prompts.Render(prompts.RAGPromptTemplate, map[string]interface{}{
+		"documents":    documentText,
+		"conversation": formatConversation(facts.Items, history.Items, attachments.query(request.Query)),
+	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	answer := &chatAnswer{}
+	callOptions := append(settings.callOptions(streamFunc, assembler), llms.WithUsageFunc(answer.addUsage))
+	callOptions = append(callOptions, attachments.callOptions()...)
+	if answer.Content, err = llm.GenerateContent(ctx, prompt, callOptions...); err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	return answer, nil
 }
 
-func buildPrompt(request *request.ChatRequest) (string, error) {
-	var prompt strings.Builder
+// newAgentTools returns the enabled tools, empty enables all of them.
+// The knowledge base is left out when the user has no documents to search.
+func newAgentTools(ctx context.Context, request *request.ChatRequest, enabled []string) ([]toolkit.Tool, error) {
+	isEnabled := func(tool string) bool {
+		return len(enabled) == 0 || slices.Contains(enabled, tool)
+	}
+
+	var tools []toolkit.Tool
+	if isEnabled(ToolWebSearch) {
+		searchTool, err := exa.NewSearchTool(config.Get().APIKey.Exa)
+		if err != nil {
+			return nil, err
+		}
+		tools = append(tools, searchTool)
+	}
+
+	if isEnabled(ToolKnowledgeBase) {
+		if knowledgeBaseTool, exists := newKnowledgeBaseTool(ctx, request); exists {
+			tools = append(tools, knowledgeBaseTool)
+		}
+	}
+
+	return tools, nil
+}
 
-	chatHistories, err := dao.GetChatHistoryBySessionID(request.SessionID)
+// buildPrompt puts the facts about the user and as much of the remembered history in front of the query
+// as the context window allows, the query carries the text of the attached files
+func buildPrompt(ctx context.Context, request *request.ChatRequest, settings *chatSettings, assembler *contextwindow.Assembler, query string) (string, error) {
+	history, err := loadHistoryPart(ctx, request, settings.session)
 	if err != nil {
 		return "", err
 	}
+	facts := loadUserMemoryPart(ctx, request)
+
+	if err := assembler.Fit(settings.SystemPrompt+formatConversation(nil, nil, query), history, facts); err != nil {
+		return "", err
+	}
+
+	return formatConversation(facts.Items, history.Items, query), nil
+}
+
+// loadHistoryPart returns the remembered messages as a prompt part that drops the oldest first
+func loadHistoryPart(ctx context.Context, request *request.ChatRequest, session *entity.ChatSession) (*contextwindow.Part, error) {
+	messages, err := loadMemoryMessages(ctx, request, session)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]string, len(messages))
+	for i, message := range messages {
+		lines[i] = message.Role + ": " + message.Content + "\n"
+	}
+
+	return &contextwindow.Part{Items: lines, KeepLatest: true}, nil
+}
+
+// loadUserMemoryPart returns the recalled facts about the user as a prompt part that is dropped last
+func loadUserMemoryPart(ctx context.Context, request *request.ChatRequest) *contextwindow.Part {
+	facts := recallUserMemories(ctx, request.Username, request.Query)
+
+	lines := make([]string, len(facts))
+	for i, fact := range facts {
+		lines[i] = "- " + fact + "\n"
+	}
+
+	return &contextwindow.Part{Items: lines, Priority: 2}
+}
+
+func formatConversation(facts, history []string, query string) string {
+	var prompt strings.Builder
+
+	if len(facts) > 0 {
+		prompt.WriteString("Known Facts About The User:\n")
+		for _, line := range facts {
+			prompt.WriteString(line)
+		}
+	}
 
 	prompt.WriteString("Chat History:\n")
-	for _, chatHistory := range chatHistories {
-		prompt.WriteString(chatHistory.MessageType + ": " + chatHistory.Content + "\n")
+	for _, line := range history {
+		prompt.WriteString(line)
 	}
 
 	prompt.WriteString("User Query:\n")
-	prompt.WriteString(request.Query)
+	prompt.WriteString(query)
 
-	return prompt.String(), nil
+	return prompt.String()
 }
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"easy-chat/agents/memory"
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotUserMessage  = errors.New("not a user message")
+)
+
+var _ memory.History = branchHistory{}
+
+// BranchMessage is a message on the active branch with the IDs of the messages sharing its parent,
+// itself included and oldest first, which the client switches between
+type BranchMessage struct {
+	*entity.ChatHistory
+	SiblingIDs  []uint
+	Attachments []*entity.Attachment
+}
+
+// GetActiveBranch returns a page of the session's active branch, from its first message to the last one
+func GetActiveBranch(ctx context.Context, username, sessionID string, page dao.HistoryPage) ([]*BranchMessage, bool, error) {
+	session, err := getOwnedChatSession(username, sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// only the page of the branch is loaded, the rest of the tree is walked by IDs
+	tree, err := repositories.Histories.GetTree(sessionID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	messageIDs, hasMore, err := page.Apply(messageIDsOf(branchPath(tree, session.ActiveMessageID)))
+	if err != nil {
+		return nil, false, err
+	}
+
+	path, err := getMessagesInOrder(messageIDs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	children := make(map[uint][]uint)
+	for _, message := range tree {
+		children[message.ParentID] = append(children[message.ParentID], message.ID)
+	}
+
+	attachments, err := getMessageAttachments(ctx, messageIDs)
+	if err != nil {
+		return nil, false, err
+	}
+
+	branch := make([]*BranchMessage, len(path))
+	for i, message := range path {
+		branch[i] = &BranchMessage{
+			ChatHistory: message,
+			SiblingIDs:  children[message.ParentID],
+			Attachments: attachments[message.ID],
+		}
+	}
+	return branch, hasMore, nil
+}
+
+// SwitchBranch makes the branch through the message the session's active one. The branch follows
+// the newest answer below the message, so switching to an edited question shows its latest answer.
+func SwitchBranch(ctx context.Context, username, sessionID string, messageID uint) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+
+	messages, err := repositories.Histories.GetTree(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(messages, func(message *entity.ChatHistory) bool {
+		return message.ID == messageID
+	}) {
+		return fmt.Errorf("%w: %d", ErrMessageNotFound, messageID)
+	}
+
+	newestChild := make(map[uint]uint)
+	for _, message := range messages {
+		newestChild[message.ParentID] = max(newestChild[message.ParentID], message.ID)
+	}
+
+	leafID := messageID
+	for newestChild[leafID] != 0 {
+		leafID = newestChild[leafID]
+	}
+
+	return repositories.Sessions.SetActiveMessage(sessionID, leafID)
+}
+
+// NewRegenerateRequest asks the question of a user message again, or of the question an answer replied to.
+// The new answer becomes a sibling of the earlier ones.
+func NewRegenerateRequest(ctx context.Context, username, sessionID string, messageID uint) (*request.ChatRequest, error) {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return nil, err
+	}
+
+	message, err := getSessionMessage(sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.MessageType == memory.MessageRoleAI {
+		if message, err = getSessionMessage(sessionID, message.ParentID); err != nil {
+			return nil, err
+		}
+	}
+	if message.MessageType != memory.MessageRoleUser {
+		return nil, fmt.Errorf("%w: %d", ErrNotUserMessage, message.ID)
+	}
+
+	return &request.ChatRequest{
+		Username:   username,
+		SessionID:  sessionID,
+		ParentID:   &message.ID,
+		Regenerate: true,
+	}, nil
+}
+
+// NewEditRequest asks the query in place of a user message, as its sibling, so the original
+// question and its answers stay on their own branch
+func NewEditRequest(ctx context.Context, username, sessionID string, messageID uint, query string) (*request.ChatRequest, error) {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return nil, err
+	}
+
+	message, err := getSessionMessage(sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.MessageType != memory.MessageRoleUser {
+		return nil, fmt.Errorf("%w: %d", ErrNotUserMessage, messageID)
+	}
+
+	return &request.ChatRequest{
+		Username:  username,
+		SessionID: sessionID,
+		Query:     query,
+		ParentID:  &message.ParentID,
+	}, nil
+}
+
+// prepareBranch checks that the parent the request picked is in its session and,
+// when regenerating, asks the query of the parent again
+func prepareBranch(request *request.ChatRequest) error {
+	if request.ParentID == nil || *request.ParentID == 0 {
+		if request.Regenerate {
+			return fmt.Errorf("%w: regenerating needs the user message as parent", ErrNotUserMessage)
+		}
+		return nil
+	}
+
+	parent, err := getSessionMessage(request.SessionID, *request.ParentID)
+	if err != nil {
+		return err
+	}
+
+	if request.Regenerate {
+		if parent.MessageType != memory.MessageRoleUser {
+			return fmt.Errorf("%w: %d", ErrNotUserMessage, parent.ID)
+		}
+		request.Query = parent.Content
+	}
+	return nil
+}
+
+func getSessionMessage(sessionID string, messageID uint) (*entity.ChatHistory, error) {
+	messages, err := repositories.Histories.GetByIDs([]uint{messageID})
+	if err != nil {
+		return nil, err
+	}
+	if len(messages) == 0 || messages[0].SessionID != sessionID {
+		return nil, fmt.Errorf("%w: %d", ErrMessageNotFound, messageID)
+	}
+	return messages[0], nil
+}
+
+// branchHistory reads the path from the first message of a session to leafID,
+// so memories only remember the branch being continued
+type branchHistory struct {
+	leafID uint
+	// excludeLeaf leaves the leaf itself out, for regenerating the answer to it
+	excludeLeaf bool
+}
+
+func (h branchHistory) GetBySessionID(sessionID string) ([]*entity.ChatHistory, error) {
+	tree, err := repositories.Histories.GetTree(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := branchPath(tree, h.leafID)
+	if h.excludeLeaf && len(path) > 0 {
+		path = path[:len(path)-1]
+	}
+	return getMessagesInOrder(messageIDsOf(path))
+}
+
+// branchPath returns the messages from the root of the tree down to leafID, oldest first
+func branchPath(messages []*entity.ChatHistory, leafID uint) []*entity.ChatHistory {
+	byID := make(map[uint]*entity.ChatHistory, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+
+	var path []*entity.ChatHistory
+	for id := leafID; id != 0; {
+		message, exists := byID[id]
+		if !exists {
+			break
+		}
+		path = append(path, message)
+		id = message.ParentID
+	}
+
+	slices.Reverse(path)
+	return path
+}
+
+func messageIDsOf(messages []*entity.ChatHistory) []uint {
+	ids := make([]uint, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	return ids
+}
+
+// getMessagesInOrder loads the messages in the order of their IDs
+func getMessagesInOrder(ids []uint) ([]*entity.ChatHistory, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	messages, err := repositories.Histories.GetByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*entity.ChatHistory, len(messages))
+	for _, message := range messages {
+		byID[message.ID] = message
+	}
+
+	ordered := make([]*entity.ChatHistory, 0, len(ids))
+	for _, id := range ids {
+		if message, exists := byID[id]; exists {
+			ordered = append(ordered, message)
+		}
+	}
+	return ordered, nil
+}
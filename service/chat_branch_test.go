@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"easy-chat/agents/memory"
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"slices"
+	"testing"
+)
+
+// saveTestExchange saves a question and its answer below the parent, or below the active message when it is nil
+func saveTestExchange(t *testing.T, username, sessionID string, parentID *uint, query string) []*entity.ChatHistory {
+	t.Helper()
+	chatHistories, err := repositories.Histories.Save(&request.ChatRequest{Username: username, SessionID: sessionID, ParentID: parentID}, []*entity.ChatHistory{
+		{MessageType: memory.MessageRoleUser, Content: query},
+		{MessageType: memory.MessageRoleAI, Content: "answer to " + query},
+	})
+	if err != nil {
+		t.Fatalf("Histories.Save() error = %v", err)
+	}
+	return chatHistories
+}
+
+func TestGetActiveBranch(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+	ctx := context.Background()
+
+	first := saveTestExchange(t, "alice", sessionID, nil, "first")
+	saveTestExchange(t, "alice", sessionID, nil, "second")
+	// an edit of the second question starts a branch next to it, which becomes active
+	edited := saveTestExchange(t, "alice", sessionID, &first[1].ID, "second, edited")
+
+	branch, hasMore, err := GetActiveBranch(ctx, "alice", sessionID, dao.HistoryPage{})
+	if err != nil {
+		t.Fatalf("GetActiveBranch() error = %v", err)
+	}
+	var contents []string
+	for _, message := range branch {
+		contents = append(contents, message.Content)
+	}
+	want := []string{"first", "answer to first", "second, edited", "answer to second, edited"}
+	if !slices.Equal(contents, want) || hasMore {
+		t.Fatalf("GetActiveBranch() = %q, %v, want %q, false", contents, hasMore, want)
+	}
+	if siblings := branch[2].SiblingIDs; len(siblings) != 2 || siblings[1] != edited[0].ID {
+		t.Errorf("SiblingIDs = %v, want the original and the edited question", siblings)
+	}
+
+	page, hasMore, err := GetActiveBranch(ctx, "alice", sessionID, dao.HistoryPage{BeforeID: edited[0].ID, Limit: 1})
+	if err != nil {
+		t.Fatalf("GetActiveBranch(before) error = %v", err)
+	}
+	if len(page) != 1 || page[0].ID != first[1].ID || !hasMore {
+		t.Errorf("GetActiveBranch(before) = %d messages, %v, want the first answer and more", len(page), hasMore)
+	}
+}
+
+func TestBranchPath(t *testing.T) {
+	// 1 ─ 2 ─ 3 ─ 4
+	//          └─ 5 ─ 6
+	tree := []*entity.ChatHistory{
+		{ID: 1}, {ID: 2, ParentID: 1}, {ID: 3, ParentID: 2}, {ID: 4, ParentID: 3},
+		{ID: 5, ParentID: 2}, {ID: 6, ParentID: 5},
+	}
+
+	tests := []struct {
+		name   string
+		leafID uint
+		want   []uint
+	}{
+		{"no leaf", 0, nil},
+		{"root", 1, []uint{1}},
+		{"first branch", 4, []uint{1, 2, 3, 4}},
+		{"second branch", 6, []uint{1, 2, 5, 6}},
+		{"inner message", 5, []uint{1, 2, 5}},
+		{"unknown leaf", 9, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageIDsOf(branchPath(tree, tt.leafID)); !slices.Equal(got, tt.want) {
+				t.Errorf("branchPath(%d) = %v, want %v", tt.leafID, got, tt.want)
+			}
+		})
+	}
+
+	// a message whose parent is missing, such as one of a deleted branch, starts the path
+	orphaned := []*entity.ChatHistory{{ID: 7, ParentID: 3}, {ID: 8, ParentID: 7}}
+	if got := messageIDsOf(branchPath(orphaned, 8)); !slices.Equal(got, []uint{7, 8}) {
+		t.Errorf("branchPath() of an orphaned branch = %v, want [7 8]", got)
+	}
+}
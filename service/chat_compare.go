@@ -0,0 +1,300 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"easy-chat/agents/llms"
+	"easy-chat/agents/llms/qwen"
+	"easy-chat/agents/memory"
+	"easy-chat/config"
+	"easy-chat/consts"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidComparison  = errors.New("invalid comparison")
+	ErrComparisonNotFound = errors.New("comparison not found")
+)
+
+// CompareChannel is where a model streams its answer in a comparison
+type CompareChannel struct {
+	Label string `json:"label"`
+	Model string `json:"model"`
+	// MessageID is the saved answer, it is sent once every model has answered
+	MessageID uint `json:"message_id,omitempty"`
+
+	answer *chatAnswer
+	err    error
+}
+
+type ComparisonReport struct {
+	// Comparisons counts the comparisons the users preferred an answer in
+	Comparisons int             `json:"comparisons"`
+	Models      []*ModelWinRate `json:"models"`
+	// HeadToHead has a row for every pair of models that were compared, in both orders
+	HeadToHead []*HeadToHead `json:"head_to_head"`
+}
+
+type ModelWinRate struct {
+	Model       string  `json:"model"`
+	Comparisons int     `json:"comparisons"`
+	Wins        int     `json:"wins"`
+	WinRate     float64 `json:"win_rate"`
+}
+
+type HeadToHead struct {
+	Model    string  `json:"model"`
+	Opponent string  `json:"opponent"`
+	Wins     int     `json:"wins"`
+	Losses   int     `json:"losses"`
+	WinRate  float64 `json:"win_rate"`
+}
+
+// handleCompareChat asks every model of the request concurrently, each streaming on its own channel.
+// The answers are saved as siblings below the question, the first one being active until the user prefers one.
+func handleCompareChat(ctx context.Context, request *request.ChatRequest, settings *chatSettings, attachments *chatAttachments) error {
+	if len(request.CompareModels) < 2 {
+		return fmt.Errorf("%w: at least two models must answer", ErrInvalidComparison)
+	}
+
+	eventFunc, exists := ctx.Value(consts.KeyEventFunc).(consts.EventFunc)
+	if !exists {
+		return fmt.Errorf("%w: %s", consts.ErrInvalidContextKey, consts.KeyEventFunc)
+	}
+	// the models stream at the same time, but events must be written one at a time
+	var eventMutex sync.Mutex
+	sendEvent := func(event string, data interface{}) {
+		eventMutex.Lock()
+		defer eventMutex.Unlock()
+		eventFunc(event, data)
+	}
+
+	channels := make([]*CompareChannel, len(request.CompareModels))
+	for i, model := range request.CompareModels {
+		channels[i] = &CompareChannel{Label: string(rune('a' + i)), Model: model}
+	}
+	sendEvent(consts.SSEventCompare, map[string]interface{}{"channels": channels})
+
+	// every prompt is built before any model answers, so all of them see the same history
+	prompts := make([]string, len(channels))
+	callOptions := make([][]llms.CallOption, len(channels))
+	for i, channel := range channels {
+		modelRequest := *request
+		modelRequest.Model = channel.Model
+		assembler := settings.newAssembler(channel.Model)
+
+		prompt, err := buildPrompt(ctx, &modelRequest, settings, assembler, attachments.query(request.Query))
+		if err != nil {
+			return err
+		}
+		prompts[i] = prompt
+
+		channel.answer = &chatAnswer{}
+		streamFunc := func(ctx context.Context, chunk []byte) error {
+			sendEvent(consts.SSEventResult+":"+channel.Label, string(chunk))
+			return nil
+		}
+		callOptions[i] = append(settings.callOptions(streamFunc, assembler), llms.WithUsageFunc(channel.answer.addUsage))
+		callOptions[i] = append(callOptions[i], attachments.callOptions()...)
+	}
+
+	var wg sync.WaitGroup
+	for i, channel := range channels {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			channel.answer.Content, channel.err = generateCompareAnswer(ctx, channel.Model, prompts[i], callOptions[i])
+			if channel.err != nil {
+				sendEvent(consts.SSEventError+":"+channel.Label, channel.err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	answered := slices.DeleteFunc(slices.Clone(channels), func(channel *CompareChannel) bool {
+		return channel.err != nil
+	})
+	if len(answered) == 0 {
+		return channels[0].err
+	}
+
+	chatHistories, err := saveComparison(request, answered, attachments)
+	if err != nil {
+		return err
+	}
+	sendEvent(consts.SSEventCompareDone, map[string]interface{}{
+		"message_id": chatHistories[0].ID,
+		"channels":   answered,
+	})
+
+	go embedChatHistories(chatHistories)
+	go extractUserMemories(request.Username, request.SessionID, request.Query, answered[0].answer.Content)
+	titles := startSessionTitle(request.SessionID, request.Query, answered[0].answer.Content)
+	sendSessionTitle(ctx, request.SessionID, titles)
+
+	return nil
+}
+
+func generateCompareAnswer(ctx context.Context, model, prompt string, callOptions []llms.CallOption) (string, error) {
+	llm, err := qwen.New(
+		qwen.WithModelName(model),
+		qwen.WithAPIKey(config.Get().APIKey.Qwen),
+	)
+	if err != nil {
+		return "", err
+	}
+	return llm.GenerateContent(ctx, prompt, callOptions...)
+}
+
+// saveComparison saves the question and every answer below it, and the comparison if more than one model answered.
+// It returns the question followed by the answers.
+func saveComparison(request *request.ChatRequest, answered []*CompareChannel, attachments *chatAttachments) ([]*entity.ChatHistory, error) {
+	replies := make([]*entity.ChatHistory, len(answered))
+	for i, channel := range answered {
+		reply, err := channel.answer.toChatHistory(channel.Model, ModeCompare)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+
+	chatHistories, err := repositories.Histories.Save(request, []*entity.ChatHistory{
+		{MessageType: memory.MessageRoleUser, Content: request.Query}, replies[0],
+	})
+	if err != nil {
+		return nil, err
+	}
+	question := chatHistories[0]
+	if err := attachments.attachTo(question.ID); err != nil {
+		return nil, err
+	}
+
+	answerRequest := *request
+	answerRequest.ParentID = &question.ID
+	for _, reply := range replies[1:] {
+		saved, err := repositories.Histories.Save(&answerRequest, []*entity.ChatHistory{reply})
+		if err != nil {
+			return nil, err
+		}
+		chatHistories = append(chatHistories, saved...)
+	}
+	if err := repositories.Sessions.SetActiveMessage(request.SessionID, replies[0].ID); err != nil {
+		return nil, err
+	}
+
+	models := make([]string, len(answered))
+	for i, channel := range answered {
+		channel.MessageID = replies[i].ID
+		models[i] = channel.Model
+	}
+	if len(answered) > 1 {
+		comparison := &entity.ModelComparison{
+			UserID:    question.UserID,
+			SessionID: request.SessionID,
+			MessageID: question.ID,
+			Models:    strings.Join(models, ","),
+		}
+		if err := repositories.Comparisons.Create(comparison); err != nil {
+			return nil, err
+		}
+	}
+
+	return chatHistories, nil
+}
+
+// PreferAnswer records the answer of a comparison the user preferred and switches the session to it,
+// preferring another answer later replaces the preference
+func PreferAnswer(ctx context.Context, username, sessionID string, messageID uint) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+
+	message, err := getSessionMessage(sessionID, messageID)
+	if err != nil {
+		return err
+	}
+
+	comparison, err := repositories.Comparisons.GetByMessageID(message.ParentID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrComparisonNotFound, err)
+	}
+	// later answers to the question, such as regenerated ones, were not part of the comparison
+	if message.Mode != ModeCompare || !slices.Contains(strings.Split(comparison.Models, ","), message.Model) {
+		return fmt.Errorf("%w: message %d was not compared", ErrInvalidComparison, messageID)
+	}
+
+	if err := repositories.Comparisons.SetPreference(comparison.ID, messageID, message.Model); err != nil {
+		return err
+	}
+	return SwitchBranch(ctx, username, sessionID, messageID)
+}
+
+// GetComparisonReport counts how often each model's answer was preferred, overall and against each other model
+func GetComparisonReport(ctx context.Context) (*ComparisonReport, error) {
+	comparisons, err := repositories.Comparisons.GetPreferred()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ComparisonReport{Comparisons: len(comparisons)}
+	models := make(map[string]*ModelWinRate)
+	pairs := make(map[[2]string]*HeadToHead)
+	for _, comparison := range comparisons {
+		compared := strings.Split(comparison.Models, ",")
+		for _, model := range compared {
+			winRate, exists := models[model]
+			if !exists {
+				winRate = &ModelWinRate{Model: model}
+				models[model] = winRate
+				report.Models = append(report.Models, winRate)
+			}
+			winRate.Comparisons++
+			if model == comparison.PreferredModel {
+				winRate.Wins++
+			}
+		}
+
+		for _, loser := range compared {
+			if loser == comparison.PreferredModel {
+				continue
+			}
+			getHeadToHead(report, pairs, comparison.PreferredModel, loser).Wins++
+			getHeadToHead(report, pairs, loser, comparison.PreferredModel).Losses++
+		}
+	}
+
+	for _, winRate := range report.Models {
+		winRate.WinRate = float64(winRate.Wins) / float64(winRate.Comparisons)
+	}
+	for _, headToHead := range report.HeadToHead {
+		headToHead.WinRate = float64(headToHead.Wins) / float64(headToHead.Wins+headToHead.Losses)
+	}
+
+	slices.SortStableFunc(report.Models, func(a, b *ModelWinRate) int {
+		return cmp.Compare(b.WinRate, a.WinRate)
+	})
+	slices.SortStableFunc(report.HeadToHead, func(a, b *HeadToHead) int {
+		return cmp.Or(cmp.Compare(a.Model, b.Model), cmp.Compare(a.Opponent, b.Opponent))
+	})
+	if report.Models == nil {
+		report.Models = []*ModelWinRate{}
+		report.HeadToHead = []*HeadToHead{}
+	}
+	return report, nil
+}
+
+func getHeadToHead(report *ComparisonReport, pairs map[[2]string]*HeadToHead, model, opponent string) *HeadToHead {
+	headToHead, exists := pairs[[2]string{model, opponent}]
+	if !exists {
+		headToHead = &HeadToHead{Model: model, Opponent: opponent}
+		pairs[[2]string{model, opponent}] = headToHead
+		report.HeadToHead = append(report.HeadToHead, headToHead)
+	}
+	return headToHead
+}
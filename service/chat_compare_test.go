@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"easy-chat/entity"
+	"testing"
+)
+
+func TestGetComparisonReport(t *testing.T) {
+	type preference struct {
+		models    string
+		preferred string
+	}
+
+	tests := []struct {
+		name         string
+		preferences  []preference
+		wantModels   map[string]ModelWinRate
+		wantOrder    []string
+		wantPairs    map[[2]string]HeadToHead
+		unpreferred  int
+		wantCompared int
+	}{
+		{
+			name:         "nothing preferred",
+			unpreferred:  1,
+			wantModels:   map[string]ModelWinRate{},
+			wantPairs:    map[[2]string]HeadToHead{},
+			wantCompared: 0,
+		},
+		{
+			name: "two models",
+			preferences: []preference{
+				{"qwen-plus,qwen-turbo", "qwen-plus"},
+				{"qwen-plus,qwen-turbo", "qwen-plus"},
+				{"qwen-turbo,qwen-plus", "qwen-turbo"},
+			},
+			unpreferred: 1,
+			wantModels: map[string]ModelWinRate{
+				"qwen-plus":  {Comparisons: 3, Wins: 2},
+				"qwen-turbo": {Comparisons: 3, Wins: 1},
+			},
+			wantOrder: []string{"qwen-plus", "qwen-turbo"},
+			wantPairs: map[[2]string]HeadToHead{
+				{"qwen-plus", "qwen-turbo"}: {Wins: 2, Losses: 1},
+				{"qwen-turbo", "qwen-plus"}: {Wins: 1, Losses: 2},
+			},
+			wantCompared: 3,
+		},
+		{
+			name: "three models",
+			preferences: []preference{
+				{"qwen-plus,qwen-turbo,qwen-max", "qwen-max"},
+				{"qwen-plus,qwen-turbo", "qwen-turbo"},
+			},
+			wantModels: map[string]ModelWinRate{
+				"qwen-max":   {Comparisons: 1, Wins: 1},
+				"qwen-turbo": {Comparisons: 2, Wins: 1},
+				"qwen-plus":  {Comparisons: 2, Wins: 0},
+			},
+			wantOrder: []string{"qwen-max", "qwen-turbo", "qwen-plus"},
+			wantPairs: map[[2]string]HeadToHead{
+				{"qwen-max", "qwen-plus"}:   {Wins: 1},
+				{"qwen-max", "qwen-turbo"}:  {Wins: 1},
+				{"qwen-plus", "qwen-max"}:   {Losses: 1},
+				{"qwen-plus", "qwen-turbo"}: {Losses: 1},
+				{"qwen-turbo", "qwen-max"}:  {Losses: 1},
+				{"qwen-turbo", "qwen-plus"}: {Wins: 1},
+			},
+			wantCompared: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useInMemoryRepositories(t)
+			for i, preference := range tt.preferences {
+				comparison := &entity.ModelComparison{MessageID: uint(i + 1), Models: preference.models}
+				if err := repositories.Comparisons.Create(comparison); err != nil {
+					t.Fatalf("Comparisons.Create() error = %v", err)
+				}
+				if err := repositories.Comparisons.SetPreference(comparison.ID, uint(i+100), preference.preferred); err != nil {
+					t.Fatalf("Comparisons.SetPreference() error = %v", err)
+				}
+			}
+			for i := 0; i < tt.unpreferred; i++ {
+				if err := repositories.Comparisons.Create(&entity.ModelComparison{Models: "qwen-plus,qwen-turbo"}); err != nil {
+					t.Fatalf("Comparisons.Create() error = %v", err)
+				}
+			}
+
+			report, err := GetComparisonReport(context.Background())
+			if err != nil {
+				t.Fatalf("GetComparisonReport() error = %v", err)
+			}
+			if report.Comparisons != tt.wantCompared {
+				t.Errorf("Comparisons = %d, want %d", report.Comparisons, tt.wantCompared)
+			}
+
+			if len(report.Models) != len(tt.wantModels) {
+				t.Fatalf("%d models, want %d", len(report.Models), len(tt.wantModels))
+			}
+			for i, winRate := range report.Models {
+				want := tt.wantModels[winRate.Model]
+				wantRate := float64(want.Wins) / float64(want.Comparisons)
+				if winRate.Model != tt.wantOrder[i] || winRate.Comparisons != want.Comparisons || winRate.Wins != want.Wins || winRate.WinRate != wantRate {
+					t.Errorf("model %d = %+v, want %s with %d of %d wins", i, winRate, tt.wantOrder[i], want.Wins, want.Comparisons)
+				}
+			}
+
+			if len(report.HeadToHead) != len(tt.wantPairs) {
+				t.Fatalf("%d head to head rows, want %d", len(report.HeadToHead), len(tt.wantPairs))
+			}
+			for _, headToHead := range report.HeadToHead {
+				want, exists := tt.wantPairs[[2]string{headToHead.Model, headToHead.Opponent}]
+				wantRate := float64(want.Wins) / float64(want.Wins+want.Losses)
+				if !exists || headToHead.Wins != want.Wins || headToHead.Losses != want.Losses || headToHead.WinRate != wantRate {
+					t.Errorf("head to head %s against %s = %+v, want %+v", headToHead.Model, headToHead.Opponent, headToHead, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,484 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"easy-chat/agents"
+	"easy-chat/agents/llms"
+	"easy-chat/agents/memory"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidExportFormat = errors.New("invalid export format")
+	ErrInvalidImportFile   = errors.New("invalid import file")
+)
+
+// the formats a session can be exported in, only JSON can be imported again
+const (
+	ExportFormatMarkdown = "md"
+	ExportFormatJSON     = "json"
+	ExportFormatHTML     = "html"
+)
+
+const (
+	exportFormatName = "easy-chat"
+	exportVersion    = 1
+
+	defaultMaxImportSize = 20
+	untitledSessionName  = "Untitled conversation"
+)
+
+// ChatExport is the JSON export of a session's active branch, ImportChatSessions reads it back
+type ChatExport struct {
+	Format     string              `json:"format"`
+	Version    int                 `json:"version"`
+	ExportTime time.Time           `json:"export_time"`
+	Session    ChatExportSession   `json:"session"`
+	Messages   []ChatExportMessage `json:"messages"`
+}
+
+type ChatExportSession struct {
+	SessionID  string    `json:"session_id"`
+	Name       string    `json:"name"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+// ChatExportMessage is a message of the export, the model, mode, usage and trace are only set on answers
+type ChatExportMessage struct {
+	Role       string        `json:"role"`
+	Content    string        `json:"content"`
+	CreateTime time.Time     `json:"create_time"`
+	Model      string        `json:"model,omitempty"`
+	Mode       string        `json:"mode,omitempty"`
+	Usage      *llms.Usage   `json:"usage,omitempty"`
+	Trace      []agents.Step `json:"trace,omitempty"`
+}
+
+// MaxImportSize returns the largest accepted import file in bytes
+func MaxImportSize() int64 {
+	return defaultMaxImportSize << 20
+}
+
+// ExportChatSession renders the active branch of the session in the format,
+// the agent's traces are left out unless withTrace is set
+func ExportChatSession(ctx context.Context, username, sessionID, format string, withTrace bool) ([]byte, error) {
+	session, err := getOwnedChatSession(username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	chatHistories, err := repositories.Histories.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	export, err := buildChatExport(session, branchPath(chatHistories, session.ActiveMessageID), withTrace)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case ExportFormatJSON:
+		return json.MarshalIndent(export, "", "  ")
+	case ExportFormatMarkdown:
+		return renderMarkdownExport(export), nil
+	case ExportFormatHTML:
+		return renderHTMLExport(export)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExportFormat, format)
+	}
+}
+
+func buildChatExport(session *entity.ChatSession, chatHistories []*entity.ChatHistory, withTrace bool) (*ChatExport, error) {
+	export := &ChatExport{
+		Format:     exportFormatName,
+		Version:    exportVersion,
+		ExportTime: time.Now(),
+		Session: ChatExportSession{
+			SessionID:  session.SessionID,
+			Name:       session.SessionName,
+			CreateTime: session.CreateTime,
+		},
+		Messages: make([]ChatExportMessage, len(chatHistories)),
+	}
+
+	for i, chatHistory := range chatHistories {
+		message := ChatExportMessage{
+			Role:       chatHistory.MessageType,
+			Content:    chatHistory.Content,
+			CreateTime: chatHistory.CreateTime,
+			Model:      chatHistory.Model,
+			Mode:       chatHistory.Mode,
+		}
+		if chatHistory.PromptTokens != 0 || chatHistory.CompletionTokens != 0 {
+			message.Usage = &llms.Usage{
+				PromptTokens:     chatHistory.PromptTokens,
+				CompletionTokens: chatHistory.CompletionTokens,
+			}
+		}
+		if withTrace && chatHistory.Trace != "" {
+			if err := json.Unmarshal([]byte(chatHistory.Trace), &message.Trace); err != nil {
+				return nil, err
+			}
+		}
+		export.Messages[i] = message
+	}
+
+	return export, nil
+}
+
+func renderMarkdownExport(export *ChatExport) []byte {
+	var markdown strings.Builder
+
+	markdown.WriteString("# " + exportTitle(export) + "\n\n")
+	markdown.WriteString("Exported " + export.ExportTime.Format(time.RFC3339) + "\n")
+
+	for _, message := range export.Messages {
+		markdown.WriteString("\n---\n\n")
+		markdown.WriteString("**" + exportRoleName(message.Role) + "** · " + message.CreateTime.Format(time.RFC3339))
+		if details := exportMessageDetails(message); details != "" {
+			markdown.WriteString(" · " + details)
+		}
+		markdown.WriteString("\n\n" + message.Content + "\n")
+
+		if len(message.Trace) > 0 {
+			markdown.WriteString("\n<details>\n<summary>Agent trace</summary>\n\n")
+			for i, step := range message.Trace {
+				fmt.Fprintf(&markdown, "%d. **Thought:** %s\n", i+1, step.Thought)
+				appendMarkdownTraceField(&markdown, "Action", step.Action)
+				appendMarkdownTraceField(&markdown, "Action Input", step.ActionInput)
+				appendMarkdownTraceField(&markdown, "Observation", step.Observation)
+				appendMarkdownTraceField(&markdown, "Final Answer", step.FinalAnswer)
+			}
+			markdown.WriteString("\n</details>\n")
+		}
+	}
+
+	return []byte(markdown.String())
+}
+
+func appendMarkdownTraceField(markdown *strings.Builder, name, value string) {
+	if value != "" {
+		markdown.WriteString("   - **" + name + ":** " + strings.ReplaceAll(value, "\n", "\n     ") + "\n")
+	}
+}
+
+var htmlExportTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"role":    exportRoleName,
+	"details": exportMessageDetails,
+	"time": func(t time.Time) string {
+		return t.Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 800px; margin: 2em auto; color: #222; }
+.message { border-top: 1px solid #ddd; padding: 1em 0; }
+.meta { color: #777; font-size: 0.85em; }
+.content { white-space: pre-wrap; }
+details { margin-top: 0.5em; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Exported {{time .Export.ExportTime}}</p>
+{{range .Export.Messages}}<div class="message">
+<div class="meta"><strong>{{role .Role}}</strong> · {{time .CreateTime}}{{with details .}} · {{.}}{{end}}</div>
+<div class="content">{{.Content}}</div>
+{{if .Trace}}<details>
+<summary>Agent trace</summary>
+<ol>
+{{range .Trace}}<li><p><strong>Thought:</strong> {{.Thought}}</p>
+{{with .Action}}<p><strong>Action:</strong> {{.}}</p>{{end}}
+{{with .ActionInput}}<p><strong>Action Input:</strong> {{.}}</p>{{end}}
+{{with .Observation}}<p class="content"><strong>Observation:</strong> {{.}}</p>{{end}}
+{{with .FinalAnswer}}<p><strong>Final Answer:</strong> {{.}}</p>{{end}}
+</li>
+{{end}}</ol>
+</details>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+func renderHTMLExport(export *ChatExport) ([]byte, error) {
+	var html bytes.Buffer
+	err := htmlExportTemplate.Execute(&html, map[string]interface{}{
+		"Title":  exportTitle(export),
+		"Export": export,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return html.Bytes(), nil
+}
+
+func exportTitle(export *ChatExport) string {
+	if export.Session.Name != "" {
+		return export.Session.Name
+	}
+	return untitledSessionName
+}
+
+func exportRoleName(role string) string {
+	if role == memory.MessageRoleAI {
+		return "Assistant"
+	}
+	return "User"
+}
+
+// exportMessageDetails returns the model and usage of an answer, such as "qwen-plus · 120 + 48 tokens"
+func exportMessageDetails(message ChatExportMessage) string {
+	var details []string
+	if message.Model != "" {
+		details = append(details, message.Model)
+	}
+	if message.Usage != nil {
+		details = append(details, fmt.Sprintf("%d + %d tokens", message.Usage.PromptTokens, message.Usage.CompletionTokens))
+	}
+	return strings.Join(details, " · ")
+}
+
+// importedConversation is a conversation read from an import file, ready to be saved as a session
+type importedConversation struct {
+	Name          string
+	ChatHistories []*entity.ChatHistory
+}
+
+// ImportChatSessions recreates sessions from a JSON export, or from a ChatGPT export
+// which may hold many conversations. It returns the IDs of the new sessions.
+// Either every conversation is imported or, when one fails, none of them is kept.
+func ImportChatSessions(ctx context.Context, username string, data []byte) ([]string, error) {
+	conversations, err := parseImportFile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionIDs := make([]string, 0, len(conversations))
+	imported := make([][]*entity.ChatHistory, 0, len(conversations))
+	for _, conversation := range conversations {
+		sessionID, err := repositories.Sessions.Create(username, &entity.ChatSession{
+			SessionName: truncateRunes(strings.TrimSpace(conversation.Name), sessionNameMaxLength),
+		})
+		if err != nil {
+			deleteImportedSessions(sessionIDs)
+			return nil, err
+		}
+		sessionIDs = append(sessionIDs, sessionID)
+
+		chatHistories, err := repositories.Histories.Save(&request.ChatRequest{
+			Username:  username,
+			SessionID: sessionID,
+		}, conversation.ChatHistories)
+		if err != nil {
+			deleteImportedSessions(sessionIDs)
+			return nil, err
+		}
+		imported = append(imported, chatHistories)
+	}
+
+	// a large export holds many conversations, they are embedded one after another
+	go func() {
+		for _, chatHistories := range imported {
+			embedChatHistories(chatHistories)
+		}
+	}()
+
+	return sessionIDs, nil
+}
+
+// deleteImportedSessions removes the sessions of an import that failed part way
+func deleteImportedSessions(sessionIDs []string) {
+	for _, sessionID := range sessionIDs {
+		if err := repositories.Sessions.Delete(sessionID); err != nil {
+			log.Printf("failed to delete imported session %s: %v", sessionID, err)
+		}
+	}
+}
+
+// parseImportFile reads the conversations with at least one message from the file
+func parseImportFile(data []byte) ([]*importedConversation, error) {
+	data = bytes.TrimSpace(data)
+
+	var conversations []*importedConversation
+	if bytes.HasPrefix(data, []byte("[")) {
+		var chatGPTConversations []*chatGPTConversation
+		if err := json.Unmarshal(data, &chatGPTConversations); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+		for _, chatGPTConversation := range chatGPTConversations {
+			conversations = append(conversations, chatGPTConversation.toImportedConversation())
+		}
+	} else {
+		var probe struct {
+			Format  string          `json:"format"`
+			Mapping json.RawMessage `json:"mapping"`
+		}
+		if err := json.Unmarshal(data, &probe); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+		}
+
+		switch {
+		case probe.Format == exportFormatName:
+			conversation, err := parseChatExport(data)
+			if err != nil {
+				return nil, err
+			}
+			conversations = append(conversations, conversation)
+		case probe.Mapping != nil:
+			var chatGPTConversation chatGPTConversation
+			if err := json.Unmarshal(data, &chatGPTConversation); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+			}
+			conversations = append(conversations, chatGPTConversation.toImportedConversation())
+		default:
+			return nil, fmt.Errorf("%w: not a %s or ChatGPT export", ErrInvalidImportFile, exportFormatName)
+		}
+	}
+
+	nonEmpty := conversations[:0]
+	for _, conversation := range conversations {
+		if len(conversation.ChatHistories) > 0 {
+			nonEmpty = append(nonEmpty, conversation)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return nil, fmt.Errorf("%w: no messages found", ErrInvalidImportFile)
+	}
+	return nonEmpty, nil
+}
+
+func parseChatExport(data []byte) (*importedConversation, error) {
+	var export ChatExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImportFile, err)
+	}
+	if export.Version > exportVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidImportFile, export.Version)
+	}
+
+	conversation := &importedConversation{Name: export.Session.Name}
+	for i, message := range export.Messages {
+		if message.Role != memory.MessageRoleUser && message.Role != memory.MessageRoleAI {
+			return nil, fmt.Errorf("%w: message %d has unknown role '%s'", ErrInvalidImportFile, i, message.Role)
+		}
+
+		chatHistory := &entity.ChatHistory{
+			CreateTime:  message.CreateTime,
+			MessageType: message.Role,
+			Content:     message.Content,
+			Model:       message.Model,
+			Mode:        message.Mode,
+		}
+		if message.Usage != nil {
+			chatHistory.PromptTokens = message.Usage.PromptTokens
+			chatHistory.CompletionTokens = message.Usage.CompletionTokens
+		}
+		if len(message.Trace) > 0 {
+			trace, err := json.Marshal(message.Trace)
+			if err != nil {
+				return nil, err
+			}
+			chatHistory.Trace = string(trace)
+		}
+		conversation.ChatHistories = append(conversation.ChatHistories, chatHistory)
+	}
+
+	return conversation, nil
+}
+
+// chatGPTConversation is a conversation of ChatGPT's conversations.json, a tree of messages
+// whose current node is the last message of the branch the user saw
+type chatGPTConversation struct {
+	Title       string                  `json:"title"`
+	CurrentNode string                  `json:"current_node"`
+	Mapping     map[string]*chatGPTNode `json:"mapping"`
+}
+
+type chatGPTNode struct {
+	Parent  string `json:"parent"`
+	Message *struct {
+		Author struct {
+			Role string `json:"role"`
+		} `json:"author"`
+		CreateTime float64 `json:"create_time"`
+		Content    struct {
+			// parts are text, or objects for images and other attachments
+			Parts []json.RawMessage `json:"parts"`
+		} `json:"content"`
+		Metadata struct {
+			ModelSlug string `json:"model_slug"`
+		} `json:"metadata"`
+	} `json:"message"`
+}
+
+// toImportedConversation keeps the text of the user's and the assistant's messages on the current branch
+func (c *chatGPTConversation) toImportedConversation() *importedConversation {
+	conversation := &importedConversation{Name: c.Title}
+
+	// walking up from the current node visits the branch newest first, visited guards against a malformed cycle
+	visited := make(map[string]bool)
+	for id := c.CurrentNode; id != "" && !visited[id]; {
+		visited[id] = true
+		node, exists := c.Mapping[id]
+		if !exists {
+			break
+		}
+		id = node.Parent
+
+		if node.Message == nil {
+			continue
+		}
+
+		var role string
+		switch node.Message.Author.Role {
+		case "user":
+			role = memory.MessageRoleUser
+		case "assistant":
+			role = memory.MessageRoleAI
+		default:
+			continue
+		}
+
+		var parts []string
+		for _, rawPart := range node.Message.Content.Parts {
+			var part string
+			if err := json.Unmarshal(rawPart, &part); err == nil && strings.TrimSpace(part) != "" {
+				parts = append(parts, part)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		chatHistory := &entity.ChatHistory{
+			MessageType: role,
+			Content:     strings.Join(parts, "\n"),
+		}
+		if node.Message.CreateTime > 0 {
+			seconds, fraction := math.Modf(node.Message.CreateTime)
+			chatHistory.CreateTime = time.Unix(int64(seconds), int64(fraction*1e9))
+		}
+		if role == memory.MessageRoleAI {
+			chatHistory.Model = node.Message.Metadata.ModelSlug
+		}
+		conversation.ChatHistories = append(conversation.ChatHistories, chatHistory)
+	}
+
+	slices.Reverse(conversation.ChatHistories)
+	return conversation
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestImportChatSessions(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+	ctx := context.Background()
+
+	if err := RenameChatSession(ctx, "alice", sessionID, "trip planning"); err != nil {
+		t.Fatalf("RenameChatSession() error = %v", err)
+	}
+	saveTestExchange(t, "alice", sessionID, nil, "first")
+	saveTestExchange(t, "alice", sessionID, nil, "second")
+
+	export, err := ExportChatSession(ctx, "alice", sessionID, ExportFormatJSON, false)
+	if err != nil {
+		t.Fatalf("ExportChatSession() error = %v", err)
+	}
+
+	sessionIDs, err := ImportChatSessions(ctx, "alice", export)
+	if err != nil {
+		t.Fatalf("ImportChatSessions() error = %v", err)
+	}
+	if len(sessionIDs) != 1 {
+		t.Fatalf("ImportChatSessions() = %v, want one session", sessionIDs)
+	}
+
+	imported, err := getOwnedChatSession("alice", sessionIDs[0])
+	if err != nil {
+		t.Fatalf("getOwnedChatSession() error = %v", err)
+	}
+	if imported.SessionName != "trip planning" {
+		t.Errorf("imported session name = %q, want %q", imported.SessionName, "trip planning")
+	}
+	chatHistories, err := repositories.Histories.GetBySessionID(sessionIDs[0])
+	if err != nil || len(chatHistories) != 4 {
+		t.Errorf("Histories.GetBySessionID() = %d messages, %v, want 4", len(chatHistories), err)
+	}
+
+	if _, err := ImportChatSessions(ctx, "alice", []byte(`{"format": "other"}`)); !errors.Is(err, ErrInvalidImportFile) {
+		t.Errorf("ImportChatSessions() of an unknown format error = %v, want %v", err, ErrInvalidImportFile)
+	}
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"easy-chat/agents/llms/qwen"
+	"easy-chat/agents/memory"
+	"easy-chat/agents/prompts"
+	"easy-chat/config"
+	"easy-chat/consts"
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrSessionNotTrashed     = errors.New("session is not in the trash")
+	ErrSessionTrashExpired   = errors.New("session has been in the trash longer than the retention window")
+	ErrFailedToGenerateTitle = errors.New("failed to generate session title")
+	ErrEmptyTitle            = errors.New("empty title")
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = time.Hour
+
+	defaultTitleModel      = "qwen-turbo"
+	sessionNameMaxLength   = 50
+	titleGenerationTimeout = 30 * time.Second
+	// titleEventWait is how long a finished answer keeps its stream open for the session's new title
+	titleEventWait = 5 * time.Second
+)
+
+// sessionTitleGenerator asks the model for a title, tests replace it to stay offline
+var sessionTitleGenerator = generateSessionTitle
+
+// CreateChatSession creates a session for the user with the memory strategy, assistant and settings of the request
+func CreateChatSession(ctx context.Context, username string, request *request.ChatSessionCreateRequest) (string, error) {
+	if request.AssistantID != 0 {
+		if _, err := getOwnedAssistant(username, request.AssistantID); err != nil {
+			return "", err
+		}
+	}
+
+	return repositories.Sessions.Create(username, &entity.ChatSession{
+		MemoryStrategy: request.MemoryStrategy,
+		AssistantID:    request.AssistantID,
+		ChatSettings:   toChatSettings(&request.Settings),
+	})
+}
+
+// GetChatSessions returns a page of the user's sessions with the status, active ones when it is empty
+func GetChatSessions(ctx context.Context, username, status string, page dao.SessionPage) ([]*entity.ChatSession, bool, error) {
+	if status == "" {
+		status = dao.SessionStatusActive
+	}
+	return repositories.Sessions.GetByUsername(username, status, page)
+}
+
+func ArchiveChatSession(ctx context.Context, username, sessionID string, archived bool) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+	return repositories.Sessions.Archive(sessionID, archived)
+}
+
+// DeleteChatSession moves the session to the trash, or removes it for good when permanent is set
+func DeleteChatSession(ctx context.Context, username, sessionID string, permanent bool) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+
+	if permanent {
+		if err := repositories.Sessions.Delete(sessionID); err != nil {
+			return err
+		}
+		deleteVectors(ctx, map[string]string{"session_id": sessionID})
+		deleteSessionBlobs(ctx, sessionID)
+		return nil
+	}
+	return repositories.Sessions.Trash(sessionID)
+}
+
+func RestoreChatSession(ctx context.Context, username, sessionID string) error {
+	session, err := getOwnedChatSession(username, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if !session.DeleteTime.Valid {
+		return ErrSessionNotTrashed
+	}
+
+	if session.DeleteTime.Time.Before(trashExpiryTime()) {
+		return ErrSessionTrashExpired
+	}
+
+	return repositories.Sessions.Restore(sessionID)
+}
+
+// GetTrashPurgeTime returns when a trashed session will be removed for good
+func GetTrashPurgeTime(session *entity.ChatSession) time.Time {
+	return session.DeleteTime.Time.Add(trashRetention())
+}
+
+// StartTrashPurger periodically removes sessions whose retention window in the trash has passed
+// and uploads that were never sent
+func StartTrashPurger() {
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := repositories.Sessions.PurgeTrashed(trashExpiryTime())
+			if err != nil {
+				log.Printf("failed to purge trashed sessions: %v", err)
+			} else if len(purged) > 0 {
+				for _, sessionID := range purged {
+					deleteVectors(context.Background(), map[string]string{"session_id": sessionID})
+				}
+				deleteSessionBlobs(context.Background(), purged...)
+				log.Printf("purged %d trashed sessions", len(purged))
+			}
+			purgeUnsentAttachments(context.Background(), unsentAttachmentExpiryTime())
+			<-ticker.C
+		}
+	}()
+}
+
+func getOwnedChatSession(username, sessionID string) (*entity.ChatSession, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := repositories.Sessions.GetByID(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSessionNotFound, err)
+	}
+
+	if session.UserID != user.ID {
+		return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+
+	return session, nil
+}
+
+func trashRetention() time.Duration {
+	days := config.Get().Session.TrashRetentionDays
+	if days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func trashExpiryTime() time.Time {
+	return time.Now().Add(-trashRetention())
+}
+
+func RenameChatSession(ctx context.Context, username, sessionID, sessionName string) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+	return repositories.Sessions.Rename(sessionID, sessionName)
+}
+
+// startSessionTitle titles the session in the background once the answer has been saved.
+// The channel receives the title, it is closed without one when the session needed none or titling failed.
+func startSessionTitle(sessionID, query, answer string) <-chan string {
+	titles := make(chan string, 1)
+	go func() {
+		defer close(titles)
+		if title := generateSessionTitleIfNeeded(sessionID, query, answer); title != "" {
+			titles <- title
+		}
+	}()
+	return titles
+}
+
+// sendSessionTitle tells the client about the new title before its stream closes. It waits at most
+// titleEventWait, a slower title is still saved and shows up in the session list.
+func sendSessionTitle(ctx context.Context, sessionID string, titles <-chan string) {
+	eventFunc, exists := ctx.Value(consts.KeyEventFunc).(consts.EventFunc)
+	if !exists {
+		return
+	}
+
+	select {
+	case title, ok := <-titles:
+		if ok {
+			eventFunc(consts.SSEventSessionUpdated, map[string]string{
+				"session_id":   sessionID,
+				"session_name": title,
+			})
+		}
+	case <-time.After(titleEventWait):
+	case <-ctx.Done():
+	}
+}
+
+// generateSessionTitleIfNeeded names a session after its first exchange and returns the title,
+// or an empty one when the session needed none
+func generateSessionTitleIfNeeded(sessionID, query, answer string) string {
+	session, err := repositories.Sessions.GetByID(sessionID)
+	if err != nil || session.SessionName != "" {
+		return ""
+	}
+
+	// only the first question is titled, however many answers it got
+	histories, err := repositories.Histories.GetBySessionID(sessionID)
+	if err != nil {
+		return ""
+	}
+	questions := 0
+	for _, history := range histories {
+		if history.MessageType == memory.MessageRoleUser {
+			questions++
+		}
+	}
+	if questions > 1 {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), titleGenerationTimeout)
+	defer cancel()
+
+	title, err := sessionTitleGenerator(ctx, query, answer)
+	if err != nil {
+		log.Printf("%v: %v", ErrFailedToGenerateTitle, err)
+		return ""
+	}
+
+	if err := repositories.Sessions.Rename(sessionID, title); err != nil {
+		log.Printf("%v: %v", ErrFailedToGenerateTitle, err)
+		return ""
+	}
+	return title
+}
+
+func generateSessionTitle(ctx context.Context, query, answer string) (string, error) {
+	cfg := config.Get()
+	modelName := cfg.Session.TitleModel
+	if modelName == "" {
+		modelName = defaultTitleModel
+	}
+
+	llm, err := qwen.New(
+		qwen.WithModelName(modelName),
+		qwen.WithAPIKey(cfg.APIKey.Qwen),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	prompt, err := prompts.Render(prompts.SessionTitlePromptTemplate, map[string]interface{}{
+		"question": query,
+		"answer":   answer,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	result, err := llm.GenerateContent(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+
+	title := strings.Trim(strings.TrimSpace(result), "\"'“”「」《》")
+	if line, _, found := strings.Cut(title, "\n"); found {
+		title = line
+	}
+	if runes := []rune(title); len(runes) > sessionNameMaxLength {
+		title = string(runes[:sessionNameMaxLength])
+	}
+	if title == "" {
+		return "", ErrEmptyTitle
+	}
+
+	return title, nil
+}
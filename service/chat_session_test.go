@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"easy-chat/consts"
+	"easy-chat/dao"
+	"easy-chat/request"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+)
+
+func TestChatSessionOwnership(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	createTestUser(t, "bob")
+	sessionID := createTestSession(t, "alice")
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"rename", func() error { return RenameChatSession(ctx, "bob", sessionID, "mine") }},
+		{"archive", func() error { return ArchiveChatSession(ctx, "bob", sessionID, true) }},
+		{"delete", func() error { return DeleteChatSession(ctx, "bob", sessionID, true) }},
+		{"restore", func() error { return RestoreChatSession(ctx, "bob", sessionID) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("error = %v, want %v", err, ErrSessionNotFound)
+			}
+		})
+	}
+
+	if _, err := repositories.Sessions.GetByID(sessionID); err != nil {
+		t.Errorf("Sessions.GetByID() error = %v, the session of another user was deleted", err)
+	}
+}
+
+func TestCreateChatSession(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	createTestUser(t, "bob")
+	ctx := context.Background()
+
+	assistant, err := CreateAssistant(ctx, "bob", &request.AssistantRequest{Name: "bob's"})
+	if err != nil {
+		t.Fatalf("CreateAssistant() error = %v", err)
+	}
+	if _, err := CreateChatSession(ctx, "alice", &request.ChatSessionCreateRequest{AssistantID: assistant.ID}); !errors.Is(err, ErrAssistantNotFound) {
+		t.Errorf("CreateChatSession() with another user's assistant error = %v, want %v", err, ErrAssistantNotFound)
+	}
+
+	sessionID, err := CreateChatSession(ctx, "alice", &request.ChatSessionCreateRequest{
+		MemoryStrategy: "window",
+		Settings:       request.ChatSettings{Model: "qwen-plus", MaxTokens: 512},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatSession() error = %v", err)
+	}
+	session, err := getOwnedChatSession("alice", sessionID)
+	if err != nil {
+		t.Fatalf("getOwnedChatSession() error = %v", err)
+	}
+	if session.MemoryStrategy != "window" || session.Model != "qwen-plus" || session.MaxTokens != 512 {
+		t.Errorf("CreateChatSession() saved %+v, want the strategy and settings of the request", session)
+	}
+}
+
+func TestHandleChatRejectsSession(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	createTestUser(t, "bob")
+	sessionID := createTestSession(t, "alice")
+	trashedID := createTestSession(t, "alice")
+	ctx := context.Background()
+
+	if err := DeleteChatSession(ctx, "alice", trashedID, false); err != nil {
+		t.Fatalf("DeleteChatSession() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		request *request.ChatRequest
+	}{
+		{"another user's", &request.ChatRequest{Username: "bob", SessionID: sessionID, Query: "hi"}},
+		{"trashed", &request.ChatRequest{Username: "alice", SessionID: trashedID, Query: "hi"}},
+		{"unknown", &request.ChatRequest{Username: "alice", SessionID: "missing", Query: "hi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := HandleChat(ctx, tt.request); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("HandleChat() error = %v, want %v", err, ErrSessionNotFound)
+			}
+		})
+	}
+}
+
+func TestTrashAndRestoreChatSession(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+	ctx := context.Background()
+
+	if err := RestoreChatSession(ctx, "alice", sessionID); !errors.Is(err, ErrSessionNotTrashed) {
+		t.Fatalf("RestoreChatSession() before trashing error = %v, want %v", err, ErrSessionNotTrashed)
+	}
+
+	if err := DeleteChatSession(ctx, "alice", sessionID, false); err != nil {
+		t.Fatalf("DeleteChatSession() error = %v", err)
+	}
+	session, err := repositories.Sessions.GetByID(sessionID)
+	if err != nil || !session.DeleteTime.Valid {
+		t.Fatalf("Sessions.GetByID() = %+v, %v, want a trashed session", session, err)
+	}
+	if purgeTime := GetTrashPurgeTime(session); purgeTime.Sub(session.DeleteTime.Time) != defaultTrashRetentionDays*24*time.Hour {
+		t.Errorf("GetTrashPurgeTime() = %v, want %d days after %v", purgeTime, defaultTrashRetentionDays, session.DeleteTime.Time)
+	}
+
+	if err := RestoreChatSession(ctx, "alice", sessionID); err != nil {
+		t.Fatalf("RestoreChatSession() error = %v", err)
+	}
+	if session, _ := repositories.Sessions.GetByID(sessionID); session.DeleteTime.Valid {
+		t.Error("RestoreChatSession() left the session in the trash")
+	}
+
+	if err := DeleteChatSession(ctx, "alice", sessionID, true); err != nil {
+		t.Fatalf("DeleteChatSession(permanent) error = %v", err)
+	}
+	if _, err := repositories.Sessions.GetByID(sessionID); err == nil {
+		t.Error("Sessions.GetByID() found the permanently deleted session")
+	}
+}
+
+func TestGetChatSessions(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	createTestUser(t, "bob")
+	active := createTestSession(t, "alice")
+	archived := createTestSession(t, "alice")
+	trashed := createTestSession(t, "alice")
+	bobs := createTestSession(t, "bob")
+	ctx := context.Background()
+
+	if err := ArchiveChatSession(ctx, "alice", archived, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteChatSession(ctx, "alice", trashed, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status  string
+		want    string
+		wantErr error
+	}{
+		{"", active, nil},
+		{dao.SessionStatusActive, active, nil},
+		{dao.SessionStatusArchived, archived, nil},
+		{dao.SessionStatusTrashed, trashed, nil},
+		{"deleted", "", dao.ErrInvalidSessionStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			sessions, hasMore, err := GetChatSessions(ctx, "alice", tt.status, dao.SessionPage{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetChatSessions() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(sessions) != 1 || sessions[0].SessionID != tt.want || hasMore {
+				t.Errorf("GetChatSessions() = %d sessions, has more %v, want only %s", len(sessions), hasMore, tt.want)
+			}
+		})
+	}
+
+	// another user's session is no cursor, it would tell when that session was last active
+	for _, cursor := range []string{bobs, "missing"} {
+		if _, _, err := GetChatSessions(ctx, "alice", "", dao.SessionPage{BeforeID: cursor}); !errors.Is(err, dao.ErrCursorNotFound) {
+			t.Errorf("GetChatSessions(before %s) error = %v, want %v", cursor, err, dao.ErrCursorNotFound)
+		}
+	}
+}
+
+func TestSessionTitleEvent(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+
+	previous := sessionTitleGenerator
+	sessionTitleGenerator = func(ctx context.Context, query, answer string) (string, error) {
+		return "Title of " + query, nil
+	}
+	t.Cleanup(func() { sessionTitleGenerator = previous })
+
+	var events []map[string]string
+	ctx := context.WithValue(context.Background(), consts.KeyEventFunc, consts.EventFunc(func(event string, data interface{}) {
+		if event == consts.SSEventSessionUpdated {
+			events = append(events, data.(map[string]string))
+		}
+	}))
+
+	saveTestExchange(t, "alice", sessionID, nil, "first")
+	sendSessionTitle(ctx, sessionID, startSessionTitle(sessionID, "first", "answer to first"))
+	want := map[string]string{"session_id": sessionID, "session_name": "Title of first"}
+	if len(events) != 1 || !maps.Equal(events[0], want) {
+		t.Fatalf("session_updated events = %v, want %v", events, want)
+	}
+
+	saveTestExchange(t, "alice", sessionID, nil, "second")
+	sendSessionTitle(ctx, sessionID, startSessionTitle(sessionID, "second", "answer to second"))
+	if len(events) != 1 {
+		t.Errorf("session_updated events = %v, want none after the first exchange", events[1:])
+	}
+	if session, _ := repositories.Sessions.GetByID(sessionID); session.SessionName != "Title of first" {
+		t.Errorf("SessionName = %q, want the title of the first exchange", session.SessionName)
+	}
+}
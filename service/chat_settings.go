@@ -0,0 +1,126 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"easy-chat/agents/contextwindow"
+	"easy-chat/agents/llms"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"easy-chat/validation"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// chatSettings is what a turn runs with, after the request is merged with its session and assistant
+type chatSettings struct {
+	entity.ChatSettings
+	SystemPrompt string
+	// session is the caller's own session the turn continues
+	session *entity.ChatSession
+}
+
+// resolveChatSettings fills what the request left out from the session's settings, then from the
+// session's assistant. The mode defaults to normal and the model to the first allowed one.
+func resolveChatSettings(request *request.ChatRequest, session *entity.ChatSession) (*chatSettings, error) {
+	settings := &chatSettings{session: session}
+	layers := []entity.ChatSettings{toChatSettings(&request.ChatSettings), session.ChatSettings}
+	if session.AssistantID != 0 {
+		assistant, err := repositories.Assistants.GetByID(session.AssistantID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrAssistantNotFound, err)
+		}
+		settings.SystemPrompt = assistant.SystemPrompt
+		layers = append(layers, assistant.ChatSettings)
+	}
+
+	settings.ChatSettings = mergeChatSettings(layers...)
+	settings.Mode = cmp.Or(settings.Mode, ModeNormal)
+	settings.Model = cmp.Or(settings.Model, validation.AllowedModels()[0])
+
+	request.Model = settings.Model
+	request.Mode = settings.Mode
+	return settings, nil
+}
+
+// mergeChatSettings takes every setting from the first layer that sets it
+func mergeChatSettings(layers ...entity.ChatSettings) entity.ChatSettings {
+	var merged entity.ChatSettings
+	for _, layer := range layers {
+		merged.Model = cmp.Or(merged.Model, layer.Model)
+		merged.Mode = cmp.Or(merged.Mode, layer.Mode)
+		merged.Temperature = cmp.Or(merged.Temperature, layer.Temperature)
+		merged.TopP = cmp.Or(merged.TopP, layer.TopP)
+		merged.MaxTokens = cmp.Or(merged.MaxTokens, layer.MaxTokens)
+		merged.Tools = cmp.Or(merged.Tools, layer.Tools)
+	}
+	return merged
+}
+
+func toChatSettings(request *request.ChatSettings) entity.ChatSettings {
+	var tools []string
+	for _, tool := range request.Tools {
+		if !slices.Contains(tools, tool) {
+			tools = append(tools, tool)
+		}
+	}
+
+	return entity.ChatSettings{
+		Model:       request.Model,
+		Mode:        request.Mode,
+		Temperature: request.Temperature,
+		TopP:        request.TopP,
+		MaxTokens:   request.MaxTokens,
+		Tools:       strings.Join(tools, ","),
+	}
+}
+
+// SplitTools returns the tools of comma separated settings, empty means all of them
+func SplitTools(tools string) []string {
+	if tools == "" {
+		return []string{}
+	}
+	return strings.Split(tools, ",")
+}
+
+// UpdateChatSessionSettings replaces the settings every request of the session inherits
+func UpdateChatSessionSettings(ctx context.Context, username, sessionID string, settings *request.ChatSettings) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+	return repositories.Sessions.UpdateSettings(sessionID, toChatSettings(settings))
+}
+
+// newAssembler fits the prompts of the turn into the model's context window, keeping room for the answer
+func (s *chatSettings) newAssembler(model string) *contextwindow.Assembler {
+	assembler := newAssembler(model)
+	if s.MaxTokens > 0 {
+		assembler.ReservedTokens = s.MaxTokens
+	}
+	return assembler
+}
+
+// generationOptions returns the sampling parameters the settings override
+func (s *chatSettings) generationOptions() []llms.CallOption {
+	var options []llms.CallOption
+	if s.Temperature != nil {
+		options = append(options, llms.WithTemperature(*s.Temperature))
+	}
+	if s.TopP != nil {
+		options = append(options, llms.WithTopP(*s.TopP))
+	}
+	return options
+}
+
+// callOptions returns everything a single streamed answer is generated with
+func (s *chatSettings) callOptions(streamFunc llms.StreamFunc, assembler *contextwindow.Assembler) []llms.CallOption {
+	options := append(s.generationOptions(),
+		llms.WithStreamFunc(streamFunc),
+		llms.WithMaxTokens(assembler.ReservedTokens),
+	)
+	if s.SystemPrompt != "" {
+		options = append(options, llms.WithSystemPrompt(s.SystemPrompt))
+	}
+	return options
+}
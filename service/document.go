@@ -0,0 +1,353 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"easy-chat/agents/documentloaders"
+	"easy-chat/agents/textsplitter"
+	"easy-chat/agents/toolkit"
+	"easy-chat/agents/toolkit/retriever"
+	"easy-chat/agents/vectorstores"
+	"easy-chat/config"
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+)
+
+var (
+	ErrDocumentNotFound      = errors.New("document not found")
+	ErrDocumentTooLarge      = errors.New("document too large")
+	ErrFailedToEmbedDocument = errors.New("failed to embed document")
+)
+
+const (
+	defaultMaxUploadSize = 10
+	defaultRetrievalTopK = 5
+)
+
+// Citation is a document excerpt that was put into a rag prompt, Index is the number the answer cites it by
+type Citation struct {
+	Index      int     `json:"index"`
+	DocumentID uint    `json:"document_id"`
+	FileName   string  `json:"file_name"`
+	ChunkIndex int     `json:"chunk_index"`
+	Content    string  `json:"content"`
+	Score      float32 `json:"score"`
+}
+
+// MaxUploadSize returns the largest accepted document in bytes
+func MaxUploadSize() int64 {
+	size := config.Get().Document.MaxUploadSize
+	if size <= 0 {
+		size = defaultMaxUploadSize
+	}
+	return int64(size) << 20
+}
+
+// UploadDocument extracts the text of the file, splits it into chunks and embeds them for retrieval.
+// The document belongs to the session if sessionID is set, otherwise to all of the user's sessions.
+func UploadDocument(ctx context.Context, username, sessionID, fileName string, content []byte) (*entity.Document, error) {
+	if vectorStore == nil {
+		return nil, ErrVectorStoreNotConfigured
+	}
+
+	if int64(len(content)) > MaxUploadSize() {
+		return nil, fmt.Errorf("%w: %d bytes", ErrDocumentTooLarge, len(content))
+	}
+
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionID != "" {
+		if _, err := getOwnedChatSession(username, sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	text, err := documentloaders.LoadFile(fileName, content)
+	if err != nil {
+		return nil, err
+	}
+
+	texts, err := newTextSplitter().SplitText(text)
+	if err != nil {
+		return nil, err
+	}
+
+	embedExecutor, err := newEmbedExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	// embed before saving anything, so a failed upload leaves no rows behind
+	vectors, err := generateEmbeddings(ctx, embedExecutor, texts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFailedToEmbedDocument, err)
+	}
+
+	document := &entity.Document{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		FileName:  fileName,
+		Size:      int64(len(content)),
+	}
+	chunks := make([]*entity.DocumentChunk, len(texts))
+	for i, chunkText := range texts {
+		chunks[i] = &entity.DocumentChunk{
+			ChunkIndex: i,
+			Content:    chunkText,
+		}
+	}
+
+	if err := repositories.Documents.Create(document, chunks); err != nil {
+		return nil, err
+	}
+
+	documents := make([]vectorstores.Document, len(chunks))
+	for i, chunk := range chunks {
+		documents[i] = vectorstores.Document{
+			ID:        formatID(chunk.ID),
+			Content:   chunk.Content,
+			Embedding: vectors[i],
+			Metadata: map[string]string{
+				"model":       embeddingModel(),
+				"document_id": formatID(document.ID),
+				"session_id":  sessionID,
+				"user_id":     formatID(user.ID),
+			},
+		}
+	}
+
+	if err := vectorStore.Upsert(ctx, dao.DocumentChunkVectorCollection, documents); err != nil {
+		if deleteErr := repositories.Documents.Delete(document.ID); deleteErr != nil {
+			log.Printf("failed to delete document %d: %v", document.ID, deleteErr)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrFailedToEmbedDocument, err)
+	}
+
+	return document, nil
+}
+
+// ListDocuments returns the user's shared documents, plus the ones of the session if sessionID is set
+func ListDocuments(ctx context.Context, username, sessionID string) ([]*entity.Document, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if sessionID != "" {
+		if _, err := getOwnedChatSession(username, sessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	return repositories.Documents.GetByUserID(user.ID, sessionID)
+}
+
+func DeleteDocument(ctx context.Context, username string, documentID uint) error {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	document, err := repositories.Documents.GetByID(documentID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDocumentNotFound, err)
+	}
+	if document.UserID != user.ID {
+		return fmt.Errorf("%w: %d", ErrDocumentNotFound, documentID)
+	}
+
+	if err := repositories.Documents.Delete(documentID); err != nil {
+		return err
+	}
+
+	deleteVectors(ctx, map[string]string{"document_id": formatID(documentID)})
+	return nil
+}
+
+// retrieveCitations finds the chunks of the user's shared documents and of the session's documents
+// that are closest to the query, best first
+func retrieveCitations(ctx context.Context, username, sessionID, query string) ([]*Citation, error) {
+	if vectorStore == nil {
+		return nil, ErrVectorStoreNotConfigured
+	}
+
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	embedExecutor, err := newEmbedExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	queryVectors, err := embedExecutor.GenerateEmbeddings(ctx, []string{truncateRunes(query, embeddingMaxRunes)})
+	if err != nil {
+		return nil, err
+	}
+	if len(queryVectors) == 0 {
+		return nil, ErrFailedToEmbedMessages
+	}
+
+	topK := config.Get().Document.RetrievalTopK
+	if topK <= 0 {
+		topK = defaultRetrievalTopK
+	}
+
+	// shared documents and session documents are separate searches, since filters only match exact values
+	var results []vectorstores.SearchResult
+	for _, scope := range []string{"", sessionID} {
+		scopeResults, err := vectorStore.Search(ctx, dao.DocumentChunkVectorCollection, queryVectors[0],
+			vectorstores.WithTopK(topK),
+			vectorstores.WithFilter(map[string]string{
+				"model":      embeddingModel(),
+				"session_id": scope,
+				"user_id":    formatID(user.ID),
+			}),
+		)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, scopeResults...)
+
+		if sessionID == "" {
+			break
+		}
+	}
+
+	slices.SortFunc(results, func(a, b vectorstores.SearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	if len(results) > topK {
+		results = results[:topK]
+	}
+
+	return buildCitations(results)
+}
+
+func buildCitations(results []vectorstores.SearchResult) ([]*Citation, error) {
+	scores := make(map[uint]float32, len(results))
+	chunkIDs := make([]uint, 0, len(results))
+	for _, result := range results {
+		chunkID, err := strconv.ParseUint(result.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		scores[uint(chunkID)] = result.Score
+		chunkIDs = append(chunkIDs, uint(chunkID))
+	}
+
+	chunks, err := repositories.Documents.GetChunksByIDs(chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	chunksByID := make(map[uint]*entity.DocumentChunk, len(chunks))
+	for _, chunk := range chunks {
+		chunksByID[chunk.ID] = chunk
+	}
+
+	documents := make(map[uint]*entity.Document)
+	citations := make([]*Citation, 0, len(results))
+	for _, chunkID := range chunkIDs {
+		chunk, exists := chunksByID[chunkID]
+		if !exists {
+			continue
+		}
+
+		document, exists := documents[chunk.DocumentID]
+		if !exists {
+			document, err = repositories.Documents.GetByID(chunk.DocumentID)
+			if err != nil {
+				continue
+			}
+			documents[chunk.DocumentID] = document
+		}
+
+		citations = append(citations, &Citation{
+			Index:      len(citations) + 1,
+			DocumentID: document.ID,
+			FileName:   document.FileName,
+			ChunkIndex: chunk.ChunkIndex,
+			Content:    chunk.Content,
+			Score:      scores[chunkID],
+		})
+	}
+
+	return citations, nil
+}
+
+// formatCitations lays every excerpt out for the rag prompt
+func formatCitations(citations []*Citation) []string {
+	excerpts := make([]string, len(citations))
+	for i, citation := range citations {
+		excerpts[i] = fmt.Sprintf("[%d] %s\n%s", citation.Index, citation.FileName, citation.Content)
+	}
+	return excerpts
+}
+
+// documentRetriever lets the agent search the documents a chat can see
+type documentRetriever struct {
+	username  string
+	sessionID string
+}
+
+func (r *documentRetriever) Retrieve(ctx context.Context, query string) ([]retriever.Source, error) {
+	citations, err := retrieveCitations(ctx, r.username, r.sessionID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]retriever.Source, len(citations))
+	for i, citation := range citations {
+		sources[i] = retriever.Source{
+			Reference: fmt.Sprintf("%s, part %d", citation.FileName, citation.ChunkIndex+1),
+			Content:   citation.Content,
+			Score:     citation.Score,
+		}
+	}
+	return sources, nil
+}
+
+// newKnowledgeBaseTool returns a tool over the user's documents, or false when there is nothing to search
+func newKnowledgeBaseTool(ctx context.Context, request *request.ChatRequest) (toolkit.Tool, bool) {
+	if vectorStore == nil {
+		return nil, false
+	}
+
+	documents, err := ListDocuments(ctx, request.Username, request.SessionID)
+	if err != nil || len(documents) == 0 {
+		return nil, false
+	}
+
+	tool, err := retriever.NewTool(&documentRetriever{
+		username:  request.Username,
+		sessionID: request.SessionID,
+	})
+	if err != nil {
+		return nil, false
+	}
+	return tool, true
+}
+
+func newTextSplitter() textsplitter.TextSplitter {
+	cfg := config.Get()
+
+	var options []textsplitter.Option
+	if cfg.Document.ChunkSize > 0 {
+		options = append(options, textsplitter.WithChunkSize(cfg.Document.ChunkSize))
+	}
+	if cfg.Document.ChunkOverlap > 0 {
+		options = append(options, textsplitter.WithChunkOverlap(cfg.Document.ChunkOverlap))
+	}
+
+	return textsplitter.NewRecursiveCharacter(options...)
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"easy-chat/agents/embedExecutors"
+	embedqwen "easy-chat/agents/embedExecutors/qwen"
+	"easy-chat/agents/vectorstores"
+	"easy-chat/config"
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrFailedToEmbedMessages    = errors.New("failed to embed messages")
+	ErrVectorStoreNotConfigured = errors.New("vector store not configured")
+)
+
+const (
+	embeddingTimeout = 30 * time.Second
+	// the embedding API rejects overly long texts, the beginning of a message is enough to find it again
+	embeddingMaxRunes          = 2000
+	embeddingBatchSize         = 25
+	defaultSemanticSearchLimit = 10
+)
+
+type SemanticSearchHit struct {
+	MessageID   uint
+	SessionID   string
+	SessionName string
+	MessageType string
+	Content     string
+	Score       float32
+	CreateTime  time.Time
+}
+
+// embedChatHistories stores an embedding for every message so it can be found by semantic search.
+// It is meant to run in its own goroutine and only logs failures.
+func embedChatHistories(chatHistories []*entity.ChatHistory) {
+	ctx, cancel := context.WithTimeout(context.Background(), embeddingTimeout)
+	defer cancel()
+
+	var texts []string
+	var embedded []*entity.ChatHistory
+	for _, chatHistory := range chatHistories {
+		if chatHistory.Content == "" {
+			continue
+		}
+		texts = append(texts, truncateRunes(chatHistory.Content, embeddingMaxRunes))
+		embedded = append(embedded, chatHistory)
+	}
+	if len(texts) == 0 || vectorStore == nil {
+		return
+	}
+
+	embedExecutor, err := newEmbedExecutor()
+	if err != nil {
+		log.Printf("%v: %v", ErrFailedToEmbedMessages, err)
+		return
+	}
+
+	vectors, err := generateEmbeddings(ctx, embedExecutor, texts)
+	if err != nil {
+		log.Printf("%v: %v", ErrFailedToEmbedMessages, err)
+		return
+	}
+
+	documents := make([]vectorstores.Document, 0, len(vectors))
+	for i, vector := range vectors {
+		documents = append(documents, vectorstores.Document{
+			ID:        formatID(embedded[i].ID),
+			Content:   texts[i],
+			Embedding: vector,
+			Metadata: map[string]string{
+				"model":      embeddingModel(),
+				"session_id": embedded[i].SessionID,
+				"user_id":    formatID(embedded[i].UserID),
+			},
+		})
+	}
+
+	if err := vectorStore.Upsert(ctx, dao.ChatHistoryVectorCollection, documents); err != nil {
+		log.Printf("%v: %v", ErrFailedToEmbedMessages, err)
+	}
+}
+
+// SemanticSearchChatHistory returns the user's past messages closest in meaning to the query
+func SemanticSearchChatHistory(ctx context.Context, username string, request *request.SemanticSearchRequest) ([]*SemanticSearchHit, error) {
+	if vectorStore == nil {
+		return nil, ErrVectorStoreNotConfigured
+	}
+
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	embedExecutor, err := newEmbedExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	queryVectors, err := embedExecutor.GenerateEmbeddings(ctx, []string{request.Query})
+	if err != nil {
+		return nil, err
+	}
+	if len(queryVectors) == 0 {
+		return nil, ErrFailedToEmbedMessages
+	}
+
+	filter := map[string]string{
+		"model":   embeddingModel(),
+		"user_id": formatID(user.ID),
+	}
+	if request.SessionID != "" {
+		filter["session_id"] = request.SessionID
+	}
+
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultSemanticSearchLimit
+	}
+
+	// ask for extra results since messages of trashed sessions are dropped afterwards
+	results, err := vectorStore.Search(ctx, dao.ChatHistoryVectorCollection, queryVectors[0],
+		vectorstores.WithTopK(2*limit),
+		vectorstores.WithFilter(filter),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hits, err := buildSemanticSearchHits(results)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func buildSemanticSearchHits(results []vectorstores.SearchResult) ([]*SemanticSearchHit, error) {
+	scores := make(map[uint]float32, len(results))
+	messageIDs := make([]uint, 0, len(results))
+	for _, result := range results {
+		messageID, err := strconv.ParseUint(result.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		scores[uint(messageID)] = result.Score
+		messageIDs = append(messageIDs, uint(messageID))
+	}
+
+	chatHistories, err := repositories.Histories.GetByIDs(messageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[string]*entity.ChatSession)
+	hits := make([]*SemanticSearchHit, 0, len(chatHistories))
+	for _, chatHistory := range chatHistories {
+		session, exists := sessions[chatHistory.SessionID]
+		if !exists {
+			session, err = repositories.Sessions.GetByID(chatHistory.SessionID)
+			if err != nil {
+				continue
+			}
+			sessions[chatHistory.SessionID] = session
+		}
+		if session.DeleteTime.Valid {
+			continue
+		}
+
+		hits = append(hits, &SemanticSearchHit{
+			MessageID:   chatHistory.ID,
+			SessionID:   chatHistory.SessionID,
+			SessionName: session.SessionName,
+			MessageType: chatHistory.MessageType,
+			Content:     chatHistory.Content,
+			Score:       scores[chatHistory.ID],
+			CreateTime:  chatHistory.CreateTime,
+		})
+	}
+
+	slices.SortFunc(hits, func(a, b *SemanticSearchHit) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	return hits, nil
+}
+
+// deleteVectors drops the embeddings of rows that no longer exist from every collection,
+// anything it misses is picked up by the cleanup command
+func deleteVectors(ctx context.Context, filter map[string]string) {
+	if vectorStore == nil {
+		return
+	}
+	for _, collection := range []string{dao.ChatHistoryVectorCollection, dao.DocumentChunkVectorCollection, dao.UserMemoryVectorCollection} {
+		if err := vectorStore.DeleteByMetadata(ctx, collection, filter); err != nil {
+			log.Printf("failed to delete embeddings from %s: %v", collection, err)
+		}
+	}
+}
+
+func newEmbedExecutor() (embedExecutors.EmbedExecutor, error) {
+	return embedqwen.New(
+		embedqwen.WithModelName(embeddingModel()),
+		embedqwen.WithAPIKey(config.Get().APIKey.Qwen),
+	)
+}
+
+func embeddingModel() string {
+	if model := config.Get().Embedding.Model; model != "" {
+		return model
+	}
+	return embedqwen.ModelNameTextEmbeddingV2
+}
+
+// generateEmbeddings embeds the texts a batch at a time, since the embedding API caps the texts per request
+func generateEmbeddings(ctx context.Context, embedExecutor embedExecutors.EmbedExecutor, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		batch := texts[start:min(start+embeddingBatchSize, len(texts))]
+
+		batchVectors, err := embedExecutor.GenerateEmbeddings(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(batchVectors) != len(batch) {
+			return nil, fmt.Errorf("%w: got %d embeddings for %d texts", ErrFailedToEmbedMessages, len(batchVectors), len(batch))
+		}
+		vectors = append(vectors, batchVectors...)
+	}
+	return vectors, nil
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func truncateRunes(text string, maxRunes int) string {
+	if runes := []rune(text); len(runes) > maxRunes {
+		return string(runes[:maxRunes])
+	}
+	return text
+}
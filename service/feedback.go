@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"easy-chat/agents"
+	"easy-chat/agents/memory"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotAIMessage       = errors.New("only answers can be rated")
+	ErrFeedbackNotFound   = errors.New("feedback not found")
+	ErrInvalidReportRange = errors.New("invalid report range")
+)
+
+// how feedback reports group ratings over time
+const (
+	ReportIntervalDay  = "day"
+	ReportIntervalWeek = "week"
+)
+
+const (
+	defaultReportDays = 30
+	maxReportDays     = 366
+	// noToolsGroup groups the answers the agent gave without calling a tool
+	noToolsGroup = "none"
+)
+
+type FeedbackReport struct {
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Interval string        `json:"interval"`
+	Total    FeedbackStats `json:"total"`
+	// Categories counts the reasons given for the ratings
+	Categories map[string]int   `json:"categories"`
+	ByModel    []*FeedbackGroup `json:"by_model"`
+	ByMode     []*FeedbackGroup `json:"by_mode"`
+	// ByTool counts an answer for every tool it used
+	ByTool []*FeedbackGroup `json:"by_tool"`
+}
+
+type FeedbackStats struct {
+	Up   int `json:"up"`
+	Down int `json:"down"`
+	// Satisfaction is the share of up ratings
+	Satisfaction float64 `json:"satisfaction"`
+}
+
+type FeedbackGroup struct {
+	Name string `json:"name"`
+	FeedbackStats
+	Periods []*FeedbackPeriod `json:"periods"`
+}
+
+type FeedbackPeriod struct {
+	Start time.Time `json:"start"`
+	FeedbackStats
+}
+
+// RateMessage saves the user's rating of an answer with what produced the answer
+func RateMessage(ctx context.Context, username, sessionID string, messageID uint, request *request.MessageFeedbackRequest) (*entity.MessageFeedback, error) {
+	session, err := getOwnedChatSession(username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	message, err := getSessionMessage(sessionID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.MessageType != memory.MessageRoleAI {
+		return nil, fmt.Errorf("%w: %d", ErrNotAIMessage, messageID)
+	}
+
+	feedback := &entity.MessageFeedback{
+		UserID:    session.UserID,
+		SessionID: sessionID,
+		MessageID: messageID,
+		Rating:    request.Rating,
+		Category:  request.Category,
+		Comment:   request.Comment,
+		Model:     message.Model,
+		Mode:      message.Mode,
+		Tools:     strings.Join(traceTools(message.Trace), ","),
+	}
+	if err := repositories.Feedback.Save(feedback); err != nil {
+		return nil, err
+	}
+	return repositories.Feedback.GetByMessageID(messageID)
+}
+
+func DeleteMessageFeedback(ctx context.Context, username, sessionID string, messageID uint) error {
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+
+	feedback, err := repositories.Feedback.GetByMessageID(messageID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFeedbackNotFound, err)
+	}
+	if feedback.SessionID != sessionID {
+		return fmt.Errorf("%w: %d", ErrFeedbackNotFound, messageID)
+	}
+
+	return repositories.Feedback.Delete(messageID)
+}
+
+// GetFeedbackReport sums up the ratings given in the days of the request by model, mode and tool
+func GetFeedbackReport(ctx context.Context, request *request.FeedbackReportRequest) (*FeedbackReport, error) {
+	interval := request.Interval
+	if interval == "" {
+		interval = ReportIntervalDay
+	}
+
+	to := truncateDay(time.Now()).AddDate(0, 0, 1)
+	if !request.To.IsZero() {
+		to = truncateDay(request.To).AddDate(0, 0, 1)
+	}
+	from := to.AddDate(0, 0, -defaultReportDays)
+	if !request.From.IsZero() {
+		from = truncateDay(request.From)
+	}
+	if !from.Before(to) || to.Sub(from) > maxReportDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: from must be before to and at most %d days apart", ErrInvalidReportRange, maxReportDays)
+	}
+
+	feedback, err := repositories.Feedback.GetBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FeedbackReport{
+		From:       from,
+		To:         to,
+		Interval:   interval,
+		Categories: make(map[string]int),
+	}
+	byModel := newFeedbackGroups()
+	byMode := newFeedbackGroups()
+	byTool := newFeedbackGroups()
+	for _, rating := range feedback {
+		start := periodStart(rating.CreateTime, interval)
+		report.Total.add(rating.Rating)
+		if rating.Category != "" {
+			report.Categories[rating.Category]++
+		}
+
+		byModel.add(rating.Model, start, rating.Rating)
+		byMode.add(rating.Mode, start, rating.Rating)
+		tools := noToolsGroup
+		if rating.Tools != "" {
+			tools = rating.Tools
+		}
+		for _, tool := range strings.Split(tools, ",") {
+			byTool.add(tool, start, rating.Rating)
+		}
+	}
+
+	report.ByModel = byModel.list()
+	report.ByMode = byMode.list()
+	report.ByTool = byTool.list()
+	return report, nil
+}
+
+func (s *FeedbackStats) add(rating string) {
+	if rating == entity.FeedbackRatingUp {
+		s.Up++
+	} else {
+		s.Down++
+	}
+	s.Satisfaction = float64(s.Up) / float64(s.Up+s.Down)
+}
+
+// feedbackGroups keeps the groups of a report in the order they were first rated in
+type feedbackGroups struct {
+	groups []*FeedbackGroup
+	byName map[string]*FeedbackGroup
+}
+
+func newFeedbackGroups() *feedbackGroups {
+	return &feedbackGroups{byName: make(map[string]*FeedbackGroup)}
+}
+
+// add counts a rating in the group and its period, ratings come oldest first so periods stay in order
+func (g *feedbackGroups) add(name string, start time.Time, rating string) {
+	group, exists := g.byName[name]
+	if !exists {
+		group = &FeedbackGroup{Name: name}
+		g.byName[name] = group
+		g.groups = append(g.groups, group)
+	}
+	group.FeedbackStats.add(rating)
+
+	if len(group.Periods) == 0 || !group.Periods[len(group.Periods)-1].Start.Equal(start) {
+		group.Periods = append(group.Periods, &FeedbackPeriod{Start: start})
+	}
+	group.Periods[len(group.Periods)-1].add(rating)
+}
+
+// list returns the most rated groups first
+func (g *feedbackGroups) list() []*FeedbackGroup {
+	groups := slices.Clone(g.groups)
+	slices.SortStableFunc(groups, func(a, b *FeedbackGroup) int {
+		return (b.Up + b.Down) - (a.Up + a.Down)
+	})
+	if groups == nil {
+		return []*FeedbackGroup{}
+	}
+	return groups
+}
+
+// traceTools returns the tools called in the trace of an answer, each once
+func traceTools(trace string) []string {
+	if trace == "" {
+		return nil
+	}
+
+	var steps []agents.Step
+	if err := json.Unmarshal([]byte(trace), &steps); err != nil {
+		return nil
+	}
+
+	var tools []string
+	for _, step := range steps {
+		tool := strings.ToLower(strings.TrimSpace(step.Action))
+		if tool != "" && !slices.Contains(tools, tool) {
+			tools = append(tools, tool)
+		}
+	}
+	return tools
+}
+
+func truncateDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// periodStart returns the day of t, or the Monday of its week
+func periodStart(t time.Time, interval string) time.Time {
+	day := truncateDay(t.In(time.Local))
+	if interval == ReportIntervalWeek {
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fixedFeedback serves ratings given at set times, which the in-memory store would stamp with the current one
+type fixedFeedback struct {
+	ratings []*entity.MessageFeedback
+}
+
+func (f *fixedFeedback) Save(feedback *entity.MessageFeedback) error { return nil }
+
+func (f *fixedFeedback) GetByMessageID(messageID uint) (*entity.MessageFeedback, error) {
+	return nil, errors.New("not stored")
+}
+
+func (f *fixedFeedback) Delete(messageID uint) error { return nil }
+
+func (f *fixedFeedback) GetBetween(from, to time.Time) ([]*entity.MessageFeedback, error) {
+	var ratings []*entity.MessageFeedback
+	for _, rating := range f.ratings {
+		if !rating.CreateTime.Before(from) && rating.CreateTime.Before(to) {
+			ratings = append(ratings, rating)
+		}
+	}
+	return ratings, nil
+}
+
+func TestPeriodStart(t *testing.T) {
+	// 2024-05-15 is a Wednesday
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, time.May, day, hour, 30, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		t        time.Time
+		interval string
+		want     time.Time
+	}{
+		{"day", at(15, 18), ReportIntervalDay, time.Date(2024, time.May, 15, 0, 0, 0, 0, time.Local)},
+		{"week from wednesday", at(15, 18), ReportIntervalWeek, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.Local)},
+		{"week from monday", at(13, 0), ReportIntervalWeek, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.Local)},
+		{"week from sunday", at(19, 23), ReportIntervalWeek, time.Date(2024, time.May, 13, 0, 0, 0, 0, time.Local)},
+		{"week across months", time.Date(2024, time.June, 1, 12, 0, 0, 0, time.Local), ReportIntervalWeek, time.Date(2024, time.May, 27, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodStart(tt.t, tt.interval); !got.Equal(tt.want) {
+				t.Errorf("periodStart(%v, %s) = %v, want %v", tt.t, tt.interval, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetFeedbackReport(t *testing.T) {
+	useInMemoryRepositories(t)
+	day := func(day int) time.Time {
+		return time.Date(2024, time.May, day, 12, 0, 0, 0, time.Local)
+	}
+	repositories.Feedback = &fixedFeedback{ratings: []*entity.MessageFeedback{
+		{Rating: entity.FeedbackRatingUp, Model: "qwen-plus", Mode: ModeNormal, CreateTime: day(13)},
+		{Rating: entity.FeedbackRatingDown, Category: "inaccurate", Model: "qwen-turbo", Mode: ModeAgent, Tools: "web_search,knowledge_base", CreateTime: day(14)},
+		{Rating: entity.FeedbackRatingUp, Model: "qwen-turbo", Mode: ModeAgent, Tools: "web_search", CreateTime: day(14)},
+		{Rating: entity.FeedbackRatingDown, Category: "inaccurate", Model: "qwen-turbo", Mode: ModeNormal, CreateTime: day(20)},
+	}}
+	ctx := context.Background()
+
+	report, err := GetFeedbackReport(ctx, &request.FeedbackReportRequest{From: day(13), To: day(20), Interval: ReportIntervalWeek})
+	if err != nil {
+		t.Fatalf("GetFeedbackReport() error = %v", err)
+	}
+	if report.Total.Up != 2 || report.Total.Down != 2 || report.Total.Satisfaction != 0.5 {
+		t.Errorf("Total = %+v, want 2 up and 2 down", report.Total)
+	}
+	if report.Categories["inaccurate"] != 2 {
+		t.Errorf("Categories = %v, want 2 inaccurate", report.Categories)
+	}
+
+	tests := []struct {
+		name    string
+		groups  []*FeedbackGroup
+		want    []string
+		periods []int
+	}{
+		{"by model", report.ByModel, []string{"qwen-turbo", "qwen-plus"}, []int{2, 1}},
+		{"by mode", report.ByMode, []string{ModeNormal, ModeAgent}, []int{2, 1}},
+		{"by tool", report.ByTool, []string{noToolsGroup, "web_search", "knowledge_base"}, []int{2, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.groups) != len(tt.want) {
+				t.Fatalf("%d groups, want %v", len(tt.groups), tt.want)
+			}
+			for i, group := range tt.groups {
+				if group.Name != tt.want[i] || len(group.Periods) != tt.periods[i] {
+					t.Errorf("group %d = %s with %d weeks, want %s with %d", i, group.Name, len(group.Periods), tt.want[i], tt.periods[i])
+				}
+			}
+		})
+	}
+
+	if _, err := GetFeedbackReport(ctx, &request.FeedbackReportRequest{From: day(20), To: day(13)}); !errors.Is(err, ErrInvalidReportRange) {
+		t.Errorf("GetFeedbackReport() with from after to error = %v, want %v", err, ErrInvalidReportRange)
+	}
+}
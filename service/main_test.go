@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"easy-chat/config"
+	"easy-chat/dao/inmemory"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `
+secret_key:
+  jwt: test
+allowed_models: [qwen-turbo, qwen-plus]
+`
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "service-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(testConfig), 0o600); err != nil {
+		log.Fatal(err)
+	}
+	if err := config.Init(configPath); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// useInMemoryRepositories backs the service layer with an empty in-memory store for the test
+func useInMemoryRepositories(t *testing.T) {
+	t.Helper()
+	previous := repositories
+	SetRepositories(inmemory.NewStore().Repositories())
+	t.Cleanup(func() { SetRepositories(previous) })
+}
+
+func createTestUser(t *testing.T, username string) *entity.User {
+	t.Helper()
+	user, err := RegisterUser(context.Background(), &request.UserRegisterRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "Secret123",
+	})
+	if err != nil {
+		t.Fatalf("RegisterUser(%s) error = %v", username, err)
+	}
+	return user
+}
+
+func createTestSession(t *testing.T, username string) string {
+	t.Helper()
+	sessionID, err := repositories.Sessions.Create(username, &entity.ChatSession{})
+	if err != nil {
+		t.Fatalf("Sessions.Create(%s) error = %v", username, err)
+	}
+	return sessionID
+}
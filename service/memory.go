@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"easy-chat/agents/llms/qwen"
+	"easy-chat/agents/memory"
+	"easy-chat/config"
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrInvalidMemoryStrategy = errors.New("invalid memory strategy")
+
+const (
+	defaultMemoryStrategy   = memory.StrategyTokenBuffer
+	defaultMemoryWindowSize = 20
+	defaultMemoryMaxTokens  = 6000
+	defaultMemoryTopK       = 4
+	defaultSummaryModel     = "qwen-turbo"
+)
+
+// GetMemoryStrategy returns the strategy the session's memory uses
+func GetMemoryStrategy(session *entity.ChatSession) string {
+	if session.MemoryStrategy != "" {
+		return session.MemoryStrategy
+	}
+	if strategy := config.Get().Memory.DefaultStrategy; strategy != "" {
+		return strategy
+	}
+	return defaultMemoryStrategy
+}
+
+func SetMemoryStrategy(ctx context.Context, username, sessionID, strategy string) error {
+	if !slices.Contains(memory.Strategies(), strategy) {
+		return fmt.Errorf("%w: %s", ErrInvalidMemoryStrategy, strategy)
+	}
+
+	if _, err := getOwnedChatSession(username, sessionID); err != nil {
+		return err
+	}
+	return repositories.Sessions.SetMemoryStrategy(sessionID, strategy)
+}
+
+// newMemory builds the memory of the strategy the session picked over the branch in history,
+// counting tokens for the model
+func newMemory(session *entity.ChatSession, model string, history memory.History) (memory.Memory, error) {
+	cfg := config.Get()
+
+	windowSize := cfg.Memory.WindowSize
+	if windowSize <= 0 {
+		windowSize = defaultMemoryWindowSize
+	}
+
+	switch strategy := GetMemoryStrategy(session); strategy {
+	case memory.StrategyBuffer:
+		return memory.NewBuffer(history), nil
+	case memory.StrategyWindow:
+		return memory.NewWindow(history, windowSize), nil
+	case memory.StrategyTokenBuffer:
+		maxTokens := cfg.Memory.MaxTokens
+		if maxTokens <= 0 {
+			maxTokens = defaultMemoryMaxTokens
+		}
+		return memory.NewTokenBuffer(history, newTokenizer(model), maxTokens), nil
+	case memory.StrategySummary:
+		modelName := cfg.Memory.SummaryModel
+		if modelName == "" {
+			modelName = defaultSummaryModel
+		}
+		llm, err := qwen.New(
+			qwen.WithModelName(modelName),
+			qwen.WithAPIKey(cfg.APIKey.Qwen),
+		)
+		if err != nil {
+			return nil, err
+		}
+		return memory.NewSummary(history, sessionSummaryStore{}, llm, windowSize), nil
+	case memory.StrategyVector:
+		if vectorStore == nil {
+			return nil, ErrVectorStoreNotConfigured
+		}
+		embedExecutor, err := newEmbedExecutor()
+		if err != nil {
+			return nil, err
+		}
+		topK := cfg.Memory.RetrievalTopK
+		if topK <= 0 {
+			topK = defaultMemoryTopK
+		}
+		filter := map[string]string{"model": embeddingModel()}
+		return memory.NewVectorRetrieval(history, vectorStore, embedExecutor,
+			dao.ChatHistoryVectorCollection, filter, topK, windowSize), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMemoryStrategy, strategy)
+	}
+}
+
+// sessionSummaryStore keeps the rolling summary on the session row
+type sessionSummaryStore struct{}
+
+func (sessionSummaryStore) LoadSummary(sessionID string) (string, uint, error) {
+	session, err := repositories.Sessions.GetByID(sessionID)
+	if err != nil {
+		return "", 0, err
+	}
+	return session.MemorySummary, session.MemorySummaryUntilID, nil
+}
+
+func (sessionSummaryStore) SaveSummary(sessionID, summary string, untilID uint) error {
+	return repositories.Sessions.SaveSummary(sessionID, summary, untilID)
+}
+
+// loadMemoryMessages returns the part of the session's conversation its memory puts into the prompt
+func loadMemoryMessages(ctx context.Context, request *request.ChatRequest, session *entity.ChatSession) ([]memory.Message, error) {
+	mem, err := newSessionMemory(request, session)
+	if err != nil {
+		return nil, err
+	}
+	return mem.LoadMessages(ctx, request.SessionID, request.Query)
+}
+
+// newSessionMemory remembers the branch the request continues, which is the session's active one
+// unless the request picked another parent
+func newSessionMemory(request *request.ChatRequest, session *entity.ChatSession) (memory.Memory, error) {
+	history := branchHistory{leafID: session.ActiveMessageID}
+	if request.ParentID != nil {
+		history.leafID = *request.ParentID
+	}
+	// a regenerated answer follows the same history as the user message it answers
+	history.excludeLeaf = request.Regenerate
+
+	return newMemory(session, request.Model, history)
+}
@@ -88,8 +88,12 @@ func startChatRequestConsumer() {
 			continue
 		}
 
+		// the username is left out of the message, the caller is the one the stream was opened by
+		req.Username = sseCtx.GetString(consts.KeyUsername)
+
 		ctx := sseCtx.Request.Context()
 		ctx = context.WithValue(ctx, consts.KeyStreamFunc, buildSSECallback(sseCtx))
+		ctx = context.WithValue(ctx, consts.KeyEventFunc, buildSSEEventFunc(sseCtx))
 
 		if err := service.HandleChat(ctx, &req); err != nil {
 			sseCtx.SSEvent(consts.SSEventError, err.Error())
@@ -128,3 +132,10 @@ func buildSSECallback(c *gin.Context) llms.StreamFunc {
 		return nil
 	}
 }
+
+func buildSSEEventFunc(c *gin.Context) consts.EventFunc {
+	return func(event string, data interface{}) {
+		c.SSEvent(event, data)
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"easy-chat/agents/vectorstores"
+	"easy-chat/blobstore"
+	"easy-chat/dao"
+)
+
+var (
+	repositories = dao.NewRepositories()
+	vectorStore  vectorstores.VectorStore
+	blobStore    blobstore.BlobStore
+)
+
+// SetRepositories replaces the repositories used by the service layer,
+// e.g. with the ones from package dao/inmemory in tests
+func SetRepositories(r *dao.Repositories) {
+	repositories = r
+}
+
+// SetVectorStore sets where message embeddings are kept for semantic search
+func SetVectorStore(store vectorstores.VectorStore) {
+	vectorStore = store
+}
+
+// SetBlobStore sets where the files of attachments are kept
+func SetBlobStore(store blobstore.BlobStore) {
+	blobStore = store
+}
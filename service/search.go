@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"easy-chat/dao"
+	"easy-chat/request"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	snippetLength  = 160
+	snippetContext = 40
+	ellipsis       = "…"
+)
+
+// ErrEmptySearchQuery is returned for a query of only whitespace, which would match every message
+var ErrEmptySearchQuery = errors.New("search query has no terms")
+
+type SearchHit struct {
+	MessageID   uint
+	SessionID   string
+	SessionName string
+	MessageType string
+	Snippet     string
+	// Highlights holds [start, end) rune offsets of the matched terms inside Snippet
+	Highlights [][2]int
+	CreateTime time.Time
+}
+
+func SearchChatHistory(ctx context.Context, username string, request *request.SearchRequest) ([]*SearchHit, error) {
+	terms := strings.Fields(request.Query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &dao.SearchFilter{
+		UserID:    user.ID,
+		Terms:     terms,
+		SessionID: request.SessionID,
+		Role:      request.Role,
+		From:      request.From,
+		Limit:     request.Limit,
+	}
+	// the end date is inclusive
+	if !request.To.IsZero() {
+		filter.To = request.To.AddDate(0, 0, 1)
+	}
+
+	results, err := repositories.Histories.Search(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]*SearchHit, len(results))
+	for i, result := range results {
+		snippet, highlights := buildSnippet(result.Content, filter.Terms)
+		hits[i] = &SearchHit{
+			MessageID:   result.ID,
+			SessionID:   result.SessionID,
+			SessionName: result.SessionName,
+			MessageType: result.MessageType,
+			Snippet:     snippet,
+			Highlights:  highlights,
+			CreateTime:  result.CreateTime,
+		}
+	}
+
+	return hits, nil
+}
+
+// buildSnippet cuts a window of the content around the first matched term
+// and reports where every term occurs inside that window
+func buildSnippet(content string, terms []string) (string, [][2]int) {
+	runes := []rune(content)
+	lowered := []rune(strings.Map(unicode.ToLower, content))
+
+	var matches [][2]int
+	for _, term := range terms {
+		needle := []rune(strings.Map(unicode.ToLower, term))
+		for _, start := range findAll(lowered, needle) {
+			matches = append(matches, [2]int{start, start + len(needle)})
+		}
+	}
+	slices.SortFunc(matches, func(a, b [2]int) int { return a[0] - b[0] })
+
+	start := 0
+	if len(matches) > 0 {
+		start = max(0, matches[0][0]-snippetContext)
+	}
+	end := min(len(runes), start+snippetLength)
+	start = max(0, min(start, end-snippetLength))
+
+	var snippet strings.Builder
+	offset := -start
+	if start > 0 {
+		snippet.WriteString(ellipsis)
+		offset += len([]rune(ellipsis))
+	}
+	snippet.WriteString(string(runes[start:end]))
+	if end < len(runes) {
+		snippet.WriteString(ellipsis)
+	}
+
+	var highlights [][2]int
+	for _, match := range matches {
+		if match[0] >= start && match[1] <= end {
+			highlights = append(highlights, [2]int{match[0] + offset, match[1] + offset})
+		}
+	}
+
+	return snippet.String(), highlights
+}
+
+func findAll(haystack, needle []rune) []int {
+	if len(needle) == 0 {
+		return nil
+	}
+
+	var positions []int
+	for i := 0; i+len(needle) <= len(haystack); i++ {
+		if slices.Equal(haystack[i:i+len(needle)], needle) {
+			positions = append(positions, i)
+			i += len(needle) - 1
+		}
+	}
+	return positions
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"easy-chat/request"
+	"errors"
+	"testing"
+)
+
+func TestSearchChatHistory(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+	saveTestExchange(t, "alice", sessionID, nil, "how do goroutines work")
+	saveTestExchange(t, "alice", sessionID, nil, "what is a channel")
+	ctx := context.Background()
+
+	hits, err := SearchChatHistory(ctx, "alice", &request.SearchRequest{Query: "goroutines"})
+	if err != nil {
+		t.Fatalf("SearchChatHistory() error = %v", err)
+	}
+	if len(hits) != 2 {
+		t.Errorf("SearchChatHistory() = %d hits, want the question and answer about goroutines", len(hits))
+	}
+
+	// a query of only whitespace has no terms and would match every message
+	for _, query := range []string{" ", "\t \n"} {
+		if _, err := SearchChatHistory(ctx, "alice", &request.SearchRequest{Query: query}); !errors.Is(err, ErrEmptySearchQuery) {
+			t.Errorf("SearchChatHistory(%q) error = %v, want %v", query, err, ErrEmptySearchQuery)
+		}
+	}
+}
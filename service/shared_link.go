@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"easy-chat/entity"
+	"easy-chat/request"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrSharedLinkNotFound = errors.New("shared link not found")
+	ErrSharedLinkExpired  = errors.New("shared link expired")
+	ErrNothingToShare     = errors.New("nothing to share")
+)
+
+// what a shared link takes a snapshot of
+const (
+	ShareScopeBranch  = "branch"
+	ShareScopeSession = "session"
+)
+
+const shareTokenBytes = 24
+
+// SharedConversation is what a shared link shows, it holds nothing about the owner
+// beyond the title and messages of the conversation
+type SharedConversation struct {
+	Title      string     `json:"title"`
+	Scope      string     `json:"scope"`
+	CreateTime time.Time  `json:"create_time"`
+	ExpireTime *time.Time `json:"expire_time,omitempty"`
+	sharedSnapshot
+}
+
+// sharedSnapshot is stored on the link. Messages are numbered from 1 in the snapshot
+// instead of by their IDs, the parent IDs keep the branches of a session snapshot.
+type sharedSnapshot struct {
+	ActiveMessageID uint             `json:"active_message_id"`
+	Messages        []*SharedMessage `json:"messages"`
+}
+
+type SharedMessage struct {
+	ID         uint      `json:"id"`
+	ParentID   uint      `json:"parent_id"`
+	Role       string    `json:"role"`
+	Content    string    `json:"content"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+// CreateSharedLink takes a snapshot of the session, or of its active branch, that the returned link serves.
+// Later messages are not shared.
+func CreateSharedLink(ctx context.Context, username, sessionID string, request *request.SharedLinkCreateRequest) (*entity.SharedLink, error) {
+	session, err := getOwnedChatSession(username, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	chatHistories, err := repositories.Histories.GetBySessionID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := request.Scope
+	if scope == "" {
+		scope = ShareScopeBranch
+	}
+	if scope == ShareScopeBranch {
+		chatHistories = branchPath(chatHistories, session.ActiveMessageID)
+	}
+	if len(chatHistories) == 0 {
+		return nil, fmt.Errorf("%w: session %s has no messages", ErrNothingToShare, sessionID)
+	}
+
+	snapshot, err := json.Marshal(buildSharedSnapshot(chatHistories, session.ActiveMessageID))
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	sharedLink := &entity.SharedLink{
+		UserID:    session.UserID,
+		SessionID: sessionID,
+		Token:     token,
+		Title:     session.SessionName,
+		Scope:     scope,
+		Snapshot:  string(snapshot),
+	}
+	if request.ExpiresInHours > 0 {
+		expireTime := time.Now().Add(time.Duration(request.ExpiresInHours) * time.Hour)
+		sharedLink.ExpireTime = &expireTime
+	}
+
+	if err := repositories.SharedLinks.Create(sharedLink); err != nil {
+		return nil, err
+	}
+	return sharedLink, nil
+}
+
+func ListSharedLinks(ctx context.Context, username string) ([]*entity.SharedLink, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	return repositories.SharedLinks.GetByUserID(user.ID)
+}
+
+// RevokeSharedLink deletes the link, so its token stops working
+func RevokeSharedLink(ctx context.Context, username string, sharedLinkID uint) error {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	sharedLink, err := repositories.SharedLinks.GetByID(sharedLinkID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSharedLinkNotFound, err)
+	}
+	if sharedLink.UserID != user.ID {
+		return fmt.Errorf("%w: %d", ErrSharedLinkNotFound, sharedLinkID)
+	}
+
+	return repositories.SharedLinks.Delete(sharedLinkID)
+}
+
+// GetSharedConversation returns the snapshot of the link, it needs no user since the token is the secret
+func GetSharedConversation(ctx context.Context, token string) (*SharedConversation, error) {
+	sharedLink, err := repositories.SharedLinks.GetByToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSharedLinkNotFound, err)
+	}
+	if sharedLink.ExpireTime != nil && time.Now().After(*sharedLink.ExpireTime) {
+		return nil, ErrSharedLinkExpired
+	}
+
+	conversation := &SharedConversation{
+		Title:      sharedLink.Title,
+		Scope:      sharedLink.Scope,
+		CreateTime: sharedLink.CreateTime,
+		ExpireTime: sharedLink.ExpireTime,
+	}
+	if err := json.Unmarshal([]byte(sharedLink.Snapshot), &conversation.sharedSnapshot); err != nil {
+		return nil, err
+	}
+	return conversation, nil
+}
+
+// buildSharedSnapshot renumbers the messages, which are oldest first, so their IDs are not shared
+func buildSharedSnapshot(chatHistories []*entity.ChatHistory, activeMessageID uint) *sharedSnapshot {
+	snapshot := &sharedSnapshot{Messages: make([]*SharedMessage, len(chatHistories))}
+
+	numbers := make(map[uint]uint, len(chatHistories))
+	for i, chatHistory := range chatHistories {
+		numbers[chatHistory.ID] = uint(i + 1)
+	}
+
+	for i, chatHistory := range chatHistories {
+		snapshot.Messages[i] = &SharedMessage{
+			ID:         numbers[chatHistory.ID],
+			ParentID:   numbers[chatHistory.ParentID],
+			Role:       chatHistory.MessageType,
+			Content:    chatHistory.Content,
+			CreateTime: chatHistory.CreateTime,
+		}
+	}
+
+	snapshot.ActiveMessageID = numbers[activeMessageID]
+	return snapshot
+}
+
+func newShareToken() (string, error) {
+	token := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"easy-chat/entity"
+	"slices"
+	"testing"
+)
+
+func TestBuildSharedSnapshot(t *testing.T) {
+	tests := []struct {
+		name            string
+		chatHistories   []*entity.ChatHistory
+		activeMessageID uint
+		wantParentIDs   []uint
+		wantActiveID    uint
+	}{
+		{
+			name:            "branch",
+			chatHistories:   []*entity.ChatHistory{{ID: 10}, {ID: 11, ParentID: 10}, {ID: 14, ParentID: 11}},
+			activeMessageID: 14,
+			wantParentIDs:   []uint{0, 1, 2},
+			wantActiveID:    3,
+		},
+		{
+			name: "session with two branches",
+			chatHistories: []*entity.ChatHistory{
+				{ID: 10}, {ID: 11, ParentID: 10}, {ID: 12, ParentID: 11}, {ID: 13, ParentID: 10}, {ID: 14, ParentID: 13},
+			},
+			activeMessageID: 12,
+			wantParentIDs:   []uint{0, 1, 2, 1, 4},
+			wantActiveID:    3,
+		},
+		{
+			// a parent outside the snapshot is not leaked by its ID
+			name:            "parent not shared",
+			chatHistories:   []*entity.ChatHistory{{ID: 20, ParentID: 7}, {ID: 21, ParentID: 20}},
+			activeMessageID: 21,
+			wantParentIDs:   []uint{0, 1},
+			wantActiveID:    2,
+		},
+		{
+			name:            "active message not shared",
+			chatHistories:   []*entity.ChatHistory{{ID: 10}, {ID: 11, ParentID: 10}},
+			activeMessageID: 30,
+			wantParentIDs:   []uint{0, 1},
+			wantActiveID:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot := buildSharedSnapshot(tt.chatHistories, tt.activeMessageID)
+
+			var ids, parentIDs []uint
+			for _, message := range snapshot.Messages {
+				ids = append(ids, message.ID)
+				parentIDs = append(parentIDs, message.ParentID)
+			}
+			for i := range ids {
+				if ids[i] != uint(i+1) {
+					t.Fatalf("message IDs = %v, want them numbered from 1", ids)
+				}
+			}
+			if !slices.Equal(parentIDs, tt.wantParentIDs) {
+				t.Errorf("parent IDs = %v, want %v", parentIDs, tt.wantParentIDs)
+			}
+			if snapshot.ActiveMessageID != tt.wantActiveID {
+				t.Errorf("ActiveMessageID = %d, want %d", snapshot.ActiveMessageID, tt.wantActiveID)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"easy-chat/agents/contextwindow"
+	"easy-chat/agents/tokenizer"
+	"easy-chat/config"
+	"log"
+	"strings"
+	"sync"
+)
+
+const defaultReservedTokens = 2048
+
+var (
+	// tokenizers caches the loaded vocabularies by file path
+	tokenizers      = make(map[string]tokenizer.Tokenizer)
+	tokenizersMutex = &sync.Mutex{}
+)
+
+// newTokenizer returns the BPE tokenizer configured for the model, or an estimator when there is none
+func newTokenizer(model string) tokenizer.Tokenizer {
+	var path, matched string
+	for prefix, vocabFile := range config.Get().Tokenizer.VocabFiles {
+		if strings.HasPrefix(model, prefix) && len(prefix) >= len(matched) {
+			path, matched = vocabFile, prefix
+		}
+	}
+	if path == "" {
+		return tokenizer.Estimator{}
+	}
+
+	tokenizersMutex.Lock()
+	defer tokenizersMutex.Unlock()
+
+	if cached, exists := tokenizers[path]; exists {
+		return cached
+	}
+
+	var loaded tokenizer.Tokenizer
+	bpe, err := tokenizer.NewBPEFromFile(path, tokenizer.QwenPattern)
+	if err != nil {
+		log.Printf("failed to load vocabulary %s, estimating tokens instead: %v", path, err)
+		loaded = tokenizer.Estimator{}
+	} else {
+		loaded = bpe
+	}
+
+	tokenizers[path] = loaded
+	return loaded
+}
+
+// newAssembler fits prompts for the model into its context window
+func newAssembler(model string) *contextwindow.Assembler {
+	cfg := config.Get()
+
+	contextWindow, exists := cfg.Tokenizer.ContextWindows[model]
+	if !exists {
+		contextWindow = tokenizer.ContextWindow(model)
+	}
+
+	reservedTokens := cfg.Tokenizer.ReservedTokens
+	if reservedTokens <= 0 {
+		reservedTokens = defaultReservedTokens
+	}
+
+	return contextwindow.NewAssembler(newTokenizer(model), contextWindow, reservedTokens)
+}
@@ -3,9 +3,9 @@ package service
 import (
 	"context"
 	"easy-chat/config"
-	"easy-chat/dao"
 	"easy-chat/entity"
 	"easy-chat/request"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
@@ -16,12 +16,29 @@ import (
 var (
 	ErrInvalidPassword       = errors.New("invalid password")
 	ErrFailedToGenerateToken = errors.New("failed to generate token")
+	ErrInvalidPreferences    = errors.New("invalid preferences")
+	ErrTokenRevoked          = errors.New("token revoked")
 )
 
 const tokenExpirationHour = 24
 
+// TokenClaims name the user a token was issued to, the user ID tells apart a user registered again under the same name
+type TokenClaims struct {
+	jwt.StandardClaims
+	UserID       uint `json:"user_id"`
+	TokenVersion uint `json:"token_version"`
+}
+
+func RegisterUser(ctx context.Context, request *request.UserRegisterRequest) (*entity.User, error) {
+	return repositories.Users.Create(request)
+}
+
+func GetUserProfile(ctx context.Context, username string) (*entity.User, error) {
+	return repositories.Users.GetByUsername(username)
+}
+
 func UserLogin(ctx context.Context, request *request.UserLoginRequest) (string, error) {
-	user, err := dao.GetUserByUsername(request.Username)
+	user, err := repositories.Users.GetByUsername(request.Username)
 	if err != nil {
 		return "", err
 	}
@@ -31,7 +48,7 @@ func UserLogin(ctx context.Context, request *request.UserLoginRequest) (string,
 	}
 
 	user.LastLogin = time.Now()
-	if err := dao.UpdateUser(user); err != nil {
+	if err := repositories.Users.Update(user); err != nil {
 		return "", err
 	}
 
@@ -43,12 +60,106 @@ func UserLogin(ctx context.Context, request *request.UserLoginRequest) (string,
 	return token, nil
 }
 
+func UpdateUserProfile(ctx context.Context, username string, request *request.UserUpdateRequest) (*entity.User, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Email != nil && *request.Email != user.Email {
+		if err := repositories.Users.CheckEmailExists(*request.Email, user.ID); err != nil {
+			return nil, err
+		}
+		user.Email = *request.Email
+	}
+
+	if request.DisplayName != nil {
+		user.DisplayName = *request.DisplayName
+	}
+
+	if request.Preferences != nil {
+		if !json.Valid(request.Preferences) {
+			return nil, ErrInvalidPreferences
+		}
+		user.Preferences = string(request.Preferences)
+	}
+
+	if request.MemoryEnabled != nil {
+		user.MemoryEnabled = *request.MemoryEnabled
+	}
+
+	if err := repositories.Users.Update(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func ChangePassword(ctx context.Context, username string, request *request.PasswordChangeRequest) error {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.CurrentPassword)); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPassword, err)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(request.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.Password = string(passwordHash)
+	// the tokens issued with the old password stop working
+	user.TokenVersion++
+	return repositories.Users.Update(user)
+}
+
+func DeleteUser(ctx context.Context, username string) error {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	// the files are kept by session, which are gone with the user
+	attachments, err := repositories.Attachments.GetByUserID(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := repositories.Users.Delete(user.ID); err != nil {
+		return err
+	}
+	deleteVectors(ctx, map[string]string{"user_id": formatID(user.ID)})
+	for _, attachment := range attachments {
+		deleteBlob(ctx, attachment.BlobKey)
+	}
+	return nil
+}
+
+// CheckTokenClaims rejects the token of a user that was deleted, registered again or changed the password since
+func CheckTokenClaims(ctx context.Context, claims *TokenClaims) error {
+	user, err := repositories.Users.GetByUsername(claims.Issuer)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenRevoked, err)
+	}
+	if user.ID != claims.UserID || user.TokenVersion != claims.TokenVersion {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
 func generateToken(user *entity.User) (string, error) {
 	expirationTime := time.Now().Add(tokenExpirationHour * time.Hour)
 
-	claims := &jwt.StandardClaims{
-		Issuer:    user.Username,
-		ExpiresAt: expirationTime.Unix(),
+	claims := &TokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    user.Username,
+			ExpiresAt: expirationTime.Unix(),
+		},
+		UserID:       user.ID,
+		TokenVersion: user.TokenVersion,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"easy-chat/agents/llms/qwen"
+	"easy-chat/agents/prompts"
+	"easy-chat/agents/vectorstores"
+	"easy-chat/config"
+	"easy-chat/dao"
+	"easy-chat/entity"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUserMemoryNotFound         = errors.New("memory not found")
+	ErrTooManyUserMemories        = errors.New("too many memories")
+	ErrFailedToExtractUserMemory  = errors.New("failed to extract memories")
+	ErrInvalidUserMemoryExtracted = errors.New("invalid memories extracted")
+)
+
+const (
+	defaultUserMemoryModel      = "qwen-turbo"
+	defaultUserMemoryTopK       = 5
+	defaultMaxUserMemories      = 200
+	userMemoryMaxRunes          = 500
+	userMemoryExtractionTimeout = 30 * time.Second
+)
+
+func ListUserMemories(ctx context.Context, username string) ([]*entity.UserMemory, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	return repositories.UserMemories.GetByUserID(user.ID)
+}
+
+// AddUserMemory saves a fact the user wrote themselves
+func AddUserMemory(ctx context.Context, username, content string) (*entity.UserMemory, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	memories, err := repositories.UserMemories.GetByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(memories) >= maxUserMemories() {
+		return nil, fmt.Errorf("%w: at most %d", ErrTooManyUserMemories, maxUserMemories())
+	}
+
+	memory := &entity.UserMemory{
+		UserID:  user.ID,
+		Content: strings.TrimSpace(content),
+	}
+	if err := repositories.UserMemories.Create([]*entity.UserMemory{memory}); err != nil {
+		return nil, err
+	}
+
+	embedUserMemories(ctx, []*entity.UserMemory{memory})
+	return memory, nil
+}
+
+func UpdateUserMemory(ctx context.Context, username string, memoryID uint, content string) (*entity.UserMemory, error) {
+	memory, err := getOwnedUserMemory(username, memoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	memory.Content = strings.TrimSpace(content)
+	if err := repositories.UserMemories.Update(memoryID, memory.Content); err != nil {
+		return nil, err
+	}
+
+	embedUserMemories(ctx, []*entity.UserMemory{memory})
+	return memory, nil
+}
+
+func DeleteUserMemory(ctx context.Context, username string, memoryID uint) error {
+	if _, err := getOwnedUserMemory(username, memoryID); err != nil {
+		return err
+	}
+
+	if err := repositories.UserMemories.Delete(memoryID); err != nil {
+		return err
+	}
+
+	if vectorStore != nil {
+		if err := vectorStore.Delete(ctx, dao.UserMemoryVectorCollection, []string{formatID(memoryID)}); err != nil {
+			log.Printf("failed to delete embeddings from %s: %v", dao.UserMemoryVectorCollection, err)
+		}
+	}
+	return nil
+}
+
+// ClearUserMemories forgets everything about the user
+func ClearUserMemories(ctx context.Context, username string) error {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	if err := repositories.UserMemories.DeleteByUserID(user.ID); err != nil {
+		return err
+	}
+
+	if vectorStore != nil {
+		if err := vectorStore.DeleteByMetadata(ctx, dao.UserMemoryVectorCollection, map[string]string{"user_id": formatID(user.ID)}); err != nil {
+			log.Printf("failed to delete embeddings from %s: %v", dao.UserMemoryVectorCollection, err)
+		}
+	}
+	return nil
+}
+
+func getOwnedUserMemory(username string, memoryID uint) (*entity.UserMemory, error) {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := repositories.UserMemories.GetByID(memoryID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUserMemoryNotFound, err)
+	}
+	if memory.UserID != user.ID {
+		return nil, fmt.Errorf("%w: %d", ErrUserMemoryNotFound, memoryID)
+	}
+
+	return memory, nil
+}
+
+// extractUserMemories asks the LLM for new facts about the user in the latest exchange and saves them.
+// It is meant to run in its own goroutine and only logs failures.
+func extractUserMemories(username, sessionID, query, answer string) {
+	ctx, cancel := context.WithTimeout(context.Background(), userMemoryExtractionTimeout)
+	defer cancel()
+
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil || !user.MemoryEnabled {
+		return
+	}
+
+	existing, err := repositories.UserMemories.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("%v: %v", ErrFailedToExtractUserMemory, err)
+		return
+	}
+	if len(existing) >= maxUserMemories() {
+		return
+	}
+
+	facts, err := generateUserMemories(ctx, existing, query, answer)
+	if err != nil {
+		log.Printf("%v: %v", ErrFailedToExtractUserMemory, err)
+		return
+	}
+
+	known := make(map[string]bool, len(existing))
+	for _, memory := range existing {
+		known[strings.ToLower(memory.Content)] = true
+	}
+
+	var memories []*entity.UserMemory
+	for _, fact := range facts {
+		fact = truncateRunes(strings.TrimSpace(fact), userMemoryMaxRunes)
+		if fact == "" || known[strings.ToLower(fact)] || len(existing)+len(memories) >= maxUserMemories() {
+			continue
+		}
+		known[strings.ToLower(fact)] = true
+
+		memories = append(memories, &entity.UserMemory{
+			UserID:    user.ID,
+			Content:   fact,
+			SessionID: sessionID,
+		})
+	}
+	if len(memories) == 0 {
+		return
+	}
+
+	if err := repositories.UserMemories.Create(memories); err != nil {
+		log.Printf("%v: %v", ErrFailedToExtractUserMemory, err)
+		return
+	}
+
+	embedUserMemories(ctx, memories)
+}
+
+func generateUserMemories(ctx context.Context, existing []*entity.UserMemory, query, answer string) ([]string, error) {
+	cfg := config.Get()
+	modelName := cfg.UserMemory.Model
+	if modelName == "" {
+		modelName = defaultUserMemoryModel
+	}
+
+	llm, err := qwen.New(
+		qwen.WithModelName(modelName),
+		qwen.WithAPIKey(cfg.APIKey.Qwen),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var memories strings.Builder
+	for _, memory := range existing {
+		memories.WriteString("- " + memory.Content + "\n")
+	}
+	if len(existing) == 0 {
+		memories.WriteString("(none)")
+	}
+
+	prompt, err := prompts.Render(prompts.UserMemoryExtractionPromptTemplate, map[string]interface{}{
+		"memories": memories.String(),
+		"question": query,
+		"answer":   answer,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := llm.GenerateContent(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// models like to wrap JSON in a code block, so only the array itself is parsed
+	start, end := strings.Index(result, "["), strings.LastIndex(result, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidUserMemoryExtracted, result)
+	}
+
+	var facts []string
+	if err := json.Unmarshal([]byte(result[start:end+1]), &facts); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidUserMemoryExtracted, err)
+	}
+	return facts, nil
+}
+
+// recallUserMemories returns the facts about the user worth putting into a prompt for the query,
+// nothing if the user turned memory off
+func recallUserMemories(ctx context.Context, username, query string) []string {
+	user, err := repositories.Users.GetByUsername(username)
+	if err != nil || !user.MemoryEnabled {
+		return nil
+	}
+
+	memories, err := repositories.UserMemories.GetByUserID(user.ID)
+	if err != nil {
+		log.Printf("failed to load memories: %v", err)
+		return nil
+	}
+
+	topK := config.Get().UserMemory.TopK
+	if topK <= 0 {
+		topK = defaultUserMemoryTopK
+	}
+
+	if len(memories) > topK {
+		relevant, err := searchUserMemories(ctx, user, memories, query, topK)
+		if err != nil {
+			// the newest memories are a fair guess when the search is unavailable
+			log.Printf("failed to search memories: %v", err)
+			relevant = memories[:topK]
+		}
+		memories = relevant
+	}
+
+	facts := make([]string, len(memories))
+	for i, memory := range memories {
+		facts[i] = memory.Content
+	}
+	return facts
+}
+
+func searchUserMemories(ctx context.Context, user *entity.User, memories []*entity.UserMemory, query string, topK int) ([]*entity.UserMemory, error) {
+	if vectorStore == nil {
+		return nil, ErrVectorStoreNotConfigured
+	}
+
+	embedExecutor, err := newEmbedExecutor()
+	if err != nil {
+		return nil, err
+	}
+
+	queryVectors, err := embedExecutor.GenerateEmbeddings(ctx, []string{truncateRunes(query, embeddingMaxRunes)})
+	if err != nil {
+		return nil, err
+	}
+	if len(queryVectors) == 0 {
+		return nil, ErrFailedToEmbedMessages
+	}
+
+	results, err := vectorStore.Search(ctx, dao.UserMemoryVectorCollection, queryVectors[0],
+		vectorstores.WithTopK(topK),
+		vectorstores.WithFilter(map[string]string{
+			"model":   embeddingModel(),
+			"user_id": formatID(user.ID),
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]*entity.UserMemory, len(memories))
+	for _, memory := range memories {
+		byID[formatID(memory.ID)] = memory
+	}
+
+	relevant := make([]*entity.UserMemory, 0, len(results))
+	for _, result := range results {
+		if memory, exists := byID[result.ID]; exists {
+			relevant = append(relevant, memory)
+		}
+	}
+	return relevant, nil
+}
+
+// embedUserMemories stores the embeddings of the memories for recall, failures are only logged
+// since a memory without one is still listed and recalled when the user has few of them
+func embedUserMemories(ctx context.Context, memories []*entity.UserMemory) {
+	if vectorStore == nil || len(memories) == 0 {
+		return
+	}
+
+	embedExecutor, err := newEmbedExecutor()
+	if err != nil {
+		log.Printf("%v: %v", ErrFailedToEmbedMessages, err)
+		return
+	}
+
+	texts := make([]string, len(memories))
+	for i, memory := range memories {
+		texts[i] = memory.Content
+	}
+
+	vectors, err := generateEmbeddings(ctx, embedExecutor, texts)
+	if err != nil {
+		log.Printf("%v: %v", ErrFailedToEmbedMessages, err)
+		return
+	}
+
+	documents := make([]vectorstores.Document, len(memories))
+	for i, memory := range memories {
+		documents[i] = vectorstores.Document{
+			ID:        formatID(memory.ID),
+			Content:   memory.Content,
+			Embedding: vectors[i],
+			Metadata: map[string]string{
+				"model":   embeddingModel(),
+				"user_id": formatID(memory.UserID),
+			},
+		}
+	}
+
+	if err := vectorStore.Upsert(ctx, dao.UserMemoryVectorCollection, documents); err != nil {
+		log.Printf("%v: %v", ErrFailedToEmbedMessages, err)
+	}
+}
+
+func maxUserMemories() int {
+	if max := config.Get().UserMemory.MaxPerUser; max > 0 {
+		return max
+	}
+	return defaultMaxUserMemories
+}
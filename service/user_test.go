@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"easy-chat/config"
+	"easy-chat/dao"
+	"easy-chat/request"
+	"encoding/json"
+	"errors"
+	"github.com/dgrijalva/jwt-go"
+	"testing"
+)
+
+func loginTestUser(t *testing.T, username, password string) *TokenClaims {
+	t.Helper()
+	tokenString, err := UserLogin(context.Background(), &request.UserLoginRequest{Username: username, Password: password})
+	if err != nil {
+		t.Fatalf("UserLogin(%s) error = %v", username, err)
+	}
+	claims := &TokenClaims{}
+	if _, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(config.Get().SecretKey.JWT), nil
+	}); err != nil {
+		t.Fatalf("jwt.ParseWithClaims() error = %v", err)
+	}
+	return claims
+}
+
+func TestUpdateUserProfile(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	createTestUser(t, "bob")
+
+	taken := "bob@example.com"
+	free := "alice@example.org"
+	displayName := "Alice"
+	tests := []struct {
+		name    string
+		request *request.UserUpdateRequest
+		wantErr error
+	}{
+		{"email taken", &request.UserUpdateRequest{Email: &taken}, dao.ErrEmailAlreadyExists},
+		{"invalid preferences", &request.UserUpdateRequest{Preferences: json.RawMessage(`{"theme":`)}, ErrInvalidPreferences},
+		{"valid", &request.UserUpdateRequest{Email: &free, DisplayName: &displayName, Preferences: json.RawMessage(`{"theme":"dark"}`)}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := UpdateUserProfile(context.Background(), "alice", tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateUserProfile() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if user.Email != free || user.DisplayName != displayName || user.Preferences != `{"theme":"dark"}` {
+				t.Errorf("UpdateUserProfile() = %+v", user)
+			}
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	ctx := context.Background()
+
+	err := ChangePassword(ctx, "alice", &request.PasswordChangeRequest{CurrentPassword: "wrong", NewPassword: "Secret456"})
+	if !errors.Is(err, ErrInvalidPassword) {
+		t.Fatalf("ChangePassword() with a wrong password error = %v, want %v", err, ErrInvalidPassword)
+	}
+
+	if err := ChangePassword(ctx, "alice", &request.PasswordChangeRequest{CurrentPassword: "Secret123", NewPassword: "Secret456"}); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if _, err := UserLogin(ctx, &request.UserLoginRequest{Username: "alice", Password: "Secret123"}); !errors.Is(err, ErrInvalidPassword) {
+		t.Errorf("UserLogin() with the old password error = %v, want %v", err, ErrInvalidPassword)
+	}
+	if _, err := UserLogin(ctx, &request.UserLoginRequest{Username: "alice", Password: "Secret456"}); err != nil {
+		t.Errorf("UserLogin() with the new password error = %v", err)
+	}
+}
+
+func TestCheckTokenClaims(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	ctx := context.Background()
+
+	beforeChange := loginTestUser(t, "alice", "Secret123")
+	if err := CheckTokenClaims(ctx, beforeChange); err != nil {
+		t.Fatalf("CheckTokenClaims() error = %v", err)
+	}
+
+	if err := ChangePassword(ctx, "alice", &request.PasswordChangeRequest{CurrentPassword: "Secret123", NewPassword: "Secret456"}); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if err := CheckTokenClaims(ctx, beforeChange); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("CheckTokenClaims() of a token from before the password change error = %v, want %v", err, ErrTokenRevoked)
+	}
+	beforeDelete := loginTestUser(t, "alice", "Secret456")
+	if err := CheckTokenClaims(ctx, beforeDelete); err != nil {
+		t.Fatalf("CheckTokenClaims() after the password change error = %v", err)
+	}
+
+	// a user registered again under the same name does not get the tokens of the deleted one
+	if err := DeleteUser(ctx, "alice"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	createTestUser(t, "alice")
+	if err := CheckTokenClaims(ctx, beforeDelete); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("CheckTokenClaims() of a token of the deleted user error = %v, want %v", err, ErrTokenRevoked)
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	useInMemoryRepositories(t)
+	createTestUser(t, "alice")
+	sessionID := createTestSession(t, "alice")
+
+	if err := DeleteUser(context.Background(), "alice"); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if _, err := GetUserProfile(context.Background(), "alice"); err == nil {
+		t.Error("GetUserProfile() found the deleted user")
+	}
+	if _, err := repositories.Sessions.GetByID(sessionID); err == nil {
+		t.Error("Sessions.GetByID() found a session of the deleted user")
+	}
+}
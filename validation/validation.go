@@ -0,0 +1,149 @@
+package validation
+
+import (
+	"easy-chat/config"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrUnsupportedValidatorEngine = errors.New("unsupported validator engine")
+
+const (
+	passwordMinLength = 8
+	// bcrypt ignores everything after the 72nd byte
+	passwordMaxLength = 72
+)
+
+var defaultAllowedModels = []string{"qwen-turbo", "qwen-plus", "qwen-max", "qwen-long"}
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Init registers the custom validation tags on gin's default validator
+func Init() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return ErrUnsupportedValidatorEngine
+	}
+
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	validations := map[string]validator.Func{
+		"username": validateUsername,
+		"password": validatePassword,
+		"model":    validateModel,
+	}
+	for tag, fn := range validations {
+		if err := v.RegisterValidation(tag, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FieldErrors converts validation failures into a map from json field name to a readable message.
+// It returns nil when err is not caused by validation.
+func FieldErrors(err error) map[string]string {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+
+	fields := make(map[string]string, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fields[fieldError.Field()] = describe(fieldError)
+	}
+	return fields
+}
+
+func AllowedModels() []string {
+	if models := config.Get().AllowedModels; len(models) > 0 {
+		return models
+	}
+	return defaultAllowedModels
+}
+
+func validateUsername(fl validator.FieldLevel) bool {
+	return usernamePattern.MatchString(fl.Field().String())
+}
+
+func validatePassword(fl validator.FieldLevel) bool {
+	password := fl.Field().String()
+	if len(password) < passwordMinLength || len(password) > passwordMaxLength {
+		return false
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return hasLetter && hasDigit
+}
+
+func validateModel(fl validator.FieldLevel) bool {
+	return slices.Contains(AllowedModels(), fl.Field().String())
+}
+
+func describe(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return "must be at least " + fieldError.Param() + unitOf(fieldError)
+	case "max":
+		return "must be at most " + fieldError.Param() + unitOf(fieldError)
+	case "gt":
+		return "must be greater than " + fieldError.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+	case "username":
+		return "may only contain letters, digits and underscores"
+	case "password":
+		return fmt.Sprintf("must be %d to %d characters long and contain both letters and digits", passwordMinLength, passwordMaxLength)
+	case "model":
+		return "must be one of: " + strings.Join(AllowedModels(), ", ")
+	default:
+		return "failed on the '" + fieldError.Tag() + "' rule"
+	}
+}
+
+// unitOf returns what min and max count for the field, nothing for numbers
+func unitOf(fieldError validator.FieldError) string {
+	switch fieldError.Kind() {
+	case reflect.String:
+		return " characters long"
+	case reflect.Slice, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
+
+// jsonFieldName names a field after its json tag, or its form tag for query parameters
+func jsonFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(field.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}